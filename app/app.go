@@ -2,19 +2,34 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	goryu_context "github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/internal/utils"
 	"github.com/arthurlch/goryu/router"
 )
 
 type App struct {
-	Router      *router.Router
-	server      *http.Server
-	middlewares []goryu_context.Middleware
-	Config      Config
-	mountPath   string
+	Router *router.Router
+	Config Config
+
+	// proxyTrusted holds the parsed ProxyProtocolTrustedCIDRs.
+	proxyTrusted []*net.IPNet
+
+	mu     sync.Mutex
+	server *http.Server
+	hooks  *Hooks
+	// inflight counts the requests being served, reported when a drain
+	// times out.
+	inflight atomic.Int64
 }
 
 type Config struct {
@@ -23,58 +38,316 @@ type Config struct {
 	StrictRouting         bool
 	CaseSensitive         bool
 	DisableStartupMessage bool
+	// RedirectTrailingSlash redirects "/users/" to "/users" (and the other
+	// way around) when only one of them is registered. Needs StrictRouting.
+	// Default: false
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects requests with "..", duplicate slashes or
+	// different case to the registered path.
+	// Default: false
+	RedirectFixedPath bool
+	// ErrorHandler renders errors returned by handlers and middleware.
+	// Default: context.DefaultErrorHandler
+	ErrorHandler goryu_context.ErrorHandler
+	// DisableMethodNotAllowed answers 404 instead of 405 when the path only
+	// exists under other methods.
+	// Default: false
+	DisableMethodNotAllowed bool
+	// DisableAutoOptions disables automatic OPTIONS responses.
+	// Default: false
+	DisableAutoOptions bool
+	// DisableAutoHead disables serving HEAD requests from GET handlers.
+	// Default: false
+	DisableAutoHead bool
+
+	// ReadTimeout is the maximum duration for reading a request, body
+	// included. A negative value disables it.
+	// Default: 30 * time.Second
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the maximum duration for reading request
+	// headers, which protects against slow clients. A negative value
+	// disables it.
+	// Default: 10 * time.Second, or ReadTimeout when it is shorter
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration for writing a response. It is
+	// off by default so streaming responses are not cut; a negative value
+	// disables it too.
+	// Default: 0
+	WriteTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection waits for the next
+	// request. A negative value disables it.
+	// Default: 120 * time.Second
+	IdleTimeout time.Duration
+	// MaxHeaderBytes limits the size of request headers.
+	// Default: 1 << 20 (1 MB)
+	MaxHeaderBytes int
+	// BodyLimit is the maximum request body size in bytes. Reading a larger
+	// body fails with an *http.MaxBytesError, which the binders and the
+	// default error handler turn into a 413 response. A negative value
+	// disables it.
+	// Default: 4 << 20 (4 MB)
+	BodyLimit int64
+	// DisableKeepalive closes connections after each response.
+	// Default: false
+	DisableKeepalive bool
+	// ErrorLog logs connection errors and handler panics.
+	// Default: the standard logger
+	ErrorLog *log.Logger
+	// ConnState is called when a connection changes state.
+	// Default: nil
+	ConnState func(net.Conn, http.ConnState)
+	// BaseContext returns the base context of the requests of a listener.
+	// Default: context.Background
+	BaseContext func(net.Listener) context.Context
+	// ConnContext derives the context of the requests of a connection.
+	// Default: nil
+	ConnContext func(ctx context.Context, c net.Conn) context.Context
+
+	// TrustedProxies lists the reverse proxies, as CIDRs or single IP
+	// addresses, whose forwarding headers are used by RemoteIP, Protocol,
+	// Hostname and BaseURL. Requests from other peers never have their
+	// headers read.
+	// Default: nil
+	TrustedProxies []string
+	// ProxyHeader is the header holding the client IP set by the trusted
+	// proxies: "X-Forwarded-For", "Forwarded" or a single-address header
+	// such as "X-Real-IP". Requires TrustedProxies.
+	// Default: "X-Forwarded-For"
+	ProxyHeader string
+
+	// ProxyProtocol reads the HAProxy PROXY protocol (v1 or v2) header sent
+	// by the upstreams in ProxyProtocolTrustedCIDRs, so Request.RemoteAddr
+	// is the client's address instead of the load balancer's. Connections
+	// from other addresses are served as is.
+	// Default: false
+	ProxyProtocol bool
+	// ProxyProtocolTrustedCIDRs lists the upstreams allowed to send PROXY
+	// headers, as CIDRs or single IP addresses. Required with ProxyProtocol.
+	// Default: nil
+	ProxyProtocolTrustedCIDRs []string
 }
 
+const (
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 1 << 20
+	DefaultBodyLimit         = 4 << 20
+)
+
+// setDefaults fills the zero server settings and reports invalid ones.
+func (cfg *Config) setDefaults() error {
+	if cfg.ReadTimeout == 0 {
+		cfg.ReadTimeout = DefaultReadTimeout
+	}
+	if cfg.ReadHeaderTimeout == 0 {
+		cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
+		if cfg.ReadTimeout > 0 && cfg.ReadTimeout < cfg.ReadHeaderTimeout {
+			cfg.ReadHeaderTimeout = cfg.ReadTimeout
+		}
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxHeaderBytes == 0 {
+		cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	}
+	if cfg.BodyLimit == 0 {
+		cfg.BodyLimit = DefaultBodyLimit
+	}
+
+	if cfg.MaxHeaderBytes < 0 {
+		return fmt.Errorf("MaxHeaderBytes must not be negative, got %d", cfg.MaxHeaderBytes)
+	}
+	if cfg.ReadTimeout > 0 && cfg.ReadHeaderTimeout > cfg.ReadTimeout {
+		return fmt.Errorf("ReadHeaderTimeout (%s) must not exceed ReadTimeout (%s)", cfg.ReadHeaderTimeout, cfg.ReadTimeout)
+	}
+	if cfg.ProxyHeader != "" && len(cfg.TrustedProxies) == 0 {
+		return fmt.Errorf("ProxyHeader needs TrustedProxies")
+	}
+	if _, err := utils.ParseCIDRs(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("TrustedProxies: %v", err)
+	}
+	if cfg.ProxyProtocol && len(cfg.ProxyProtocolTrustedCIDRs) == 0 {
+		return fmt.Errorf("ProxyProtocol needs ProxyProtocolTrustedCIDRs")
+	}
+	if _, err := utils.ParseCIDRs(cfg.ProxyProtocolTrustedCIDRs); err != nil {
+		return fmt.Errorf("ProxyProtocolTrustedCIDRs: %v", err)
+	}
+	return nil
+}
+
+// timeout maps a configured timeout to the http.Server value, where 0
+// means none.
+func timeout(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// New creates an App. It panics if the config is invalid.
 func New(config ...Config) *App {
 	cfg := Config{} // Default config
 	if len(config) > 0 {
 		cfg = config[0]
 	}
+	if err := cfg.setDefaults(); err != nil {
+		panic(fmt.Sprintf("app: invalid config: %v", err))
+	}
 
 	app := &App{
-		Router:      router.New(),
-		middlewares: make([]goryu_context.Middleware, 0),
-		Config:      cfg,
+		Router: router.New(),
+		Config: cfg,
+	}
+	app.hooks = newHooks(app)
+	if cfg.ProxyProtocol {
+		app.proxyTrusted, _ = utils.ParseCIDRs(cfg.ProxyProtocolTrustedCIDRs)
 	}
+	if len(cfg.TrustedProxies) > 0 {
+		trusted, _ := utils.ParseCIDRs(cfg.TrustedProxies)
+		app.Router.Proxy = &goryu_context.ProxyConfig{TrustedProxies: trusted, Header: cfg.ProxyHeader}
+	}
+	app.Router.ErrorHandler = cfg.ErrorHandler
+	app.Router.HandleMethodNotAllowed = !cfg.DisableMethodNotAllowed
+	app.Router.HandleOPTIONS = !cfg.DisableAutoOptions
+	app.Router.HandleHEAD = !cfg.DisableAutoHead
+	app.Router.StrictRouting = cfg.StrictRouting
+	app.Router.CaseSensitive = cfg.CaseSensitive
+	app.Router.RedirectTrailingSlash = cfg.RedirectTrailingSlash
+	app.Router.RedirectFixedPath = cfg.RedirectFixedPath
 
 	return app
 }
 
 func (app *App) Listen(addr string) error {
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return app.Serve(ln)
+}
+
+// newServer creates the server for addr and makes it the one Shutdown
+// stops.
+func (app *App) newServer(addr string) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           app,
+		ReadTimeout:       timeout(app.Config.ReadTimeout),
+		ReadHeaderTimeout: timeout(app.Config.ReadHeaderTimeout),
+		WriteTimeout:      timeout(app.Config.WriteTimeout),
+		IdleTimeout:       timeout(app.Config.IdleTimeout),
+		MaxHeaderBytes:    app.Config.MaxHeaderBytes,
+		ErrorLog:          app.Config.ErrorLog,
+		ConnState:         app.Config.ConnState,
+		BaseContext:       app.Config.BaseContext,
+		ConnContext:       app.Config.ConnContext,
+	}
+	server.SetKeepAlivesEnabled(!app.Config.DisableKeepalive)
+	app.mu.Lock()
+	app.server = server
+	app.mu.Unlock()
+	return server
+}
+
+// serve runs the OnListen hooks and serves requests on ln until the server
+// is shut down.
+func (app *App) serve(server *http.Server, ln net.Listener, data ListenData) error {
+	if err := app.hooks.executeOnListen(data); err != nil {
+		ln.Close()
+		return err
+	}
+
 	if !app.Config.DisableStartupMessage {
 		appName := "Goryu"
 		if app.Config.AppName != "" {
 			appName = app.Config.AppName
 		}
-		fmt.Printf("🚀 %s is running on %s\n", appName, addr)
+		fmt.Printf("🚀 %s is running on %s\n", appName, data.Addr)
 	}
 
-	app.server = &http.Server{Addr: addr, Handler: app}
-	return app.server.ListenAndServe()
+	if data.TLS {
+		return server.ServeTLS(ln, "", "")
+	}
+	return server.Serve(ln)
+}
+
+// Run starts the server on addr.
+//
+// Deprecated: use Listen.
+func (app *App) Run(addr string) error {
+	return app.Listen(addr)
 }
 
 func (app *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	app.inflight.Add(1)
+	defer app.inflight.Add(-1)
+
 	if app.Config.ServerHeader != "" {
 		w.Header().Set("Server", app.Config.ServerHeader)
 	}
+
+	if limit := app.Config.BodyLimit; limit > 0 && r.Body != nil && r.Body != http.NoBody {
+		if r.ContentLength > limit {
+			// The body is refused without being read, and the error is
+			// left to the handler so the 413 goes through the middleware.
+			w.Header().Set("Connection", "close")
+			r.Body = &tooLargeBody{ReadCloser: r.Body, limit: limit}
+		} else {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+	}
 	app.Router.ServeHTTP(w, r)
 }
 
+// tooLargeBody replaces a body whose Content-Length exceeds the limit.
+type tooLargeBody struct {
+	io.ReadCloser
+	limit int64
+}
+
+func (b *tooLargeBody) Read([]byte) (int, error) {
+	return 0, &http.MaxBytesError{Limit: b.limit}
+}
+
 func (app *App) Shutdown() error {
-	if app.server == nil {
-		return fmt.Errorf("server is not running")
-	}
-	return app.server.Shutdown(context.Background())
+	return app.ShutdownWithContext(context.Background())
 }
 
+// ShutdownWithContext runs the OnPreShutdown hooks, gracefully shuts the
+// server down and runs the OnShutdown hooks. It returns the shutdown error
+// joined with the errors of the hooks.
 func (app *App) ShutdownWithContext(ctx context.Context) error {
-	if app.server == nil {
-		return fmt.Errorf("server is not running")
+	_, err := app.shutdown(ctx, false)
+	return err
+}
+
+// shutdown stops the server between the shutdown hooks. With force, the
+// connections still open when ctx is done are closed and the number of
+// requests they were serving is returned.
+func (app *App) shutdown(ctx context.Context, force bool) (int, error) {
+	server := app.Server()
+	if server == nil {
+		return 0, fmt.Errorf("server is not running")
+	}
+
+	preErr := app.hooks.executeOnShutdown(true)
+	err := server.Shutdown(ctx)
+	forced := 0
+	if err != nil && force {
+		forced = int(app.inflight.Load())
+		err = errors.Join(err, server.Close())
 	}
-	return app.server.Shutdown(ctx)
+	return forced, errors.Join(preErr, err, app.hooks.executeOnShutdown(false))
 }
 
 func (app *App) Server() *http.Server {
+	app.mu.Lock()
+	defer app.mu.Unlock()
 	return app.server
 }
 
@@ -82,6 +355,7 @@ func (app *App) Handler() http.Handler {
 	return app
 }
 
+// MountPath returns the full prefix the app is mounted under, or "".
 func (app *App) MountPath() string {
-	return app.mountPath
+	return app.Router.Prefix()
 }
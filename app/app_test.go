@@ -0,0 +1,593 @@
+package app_test
+
+import (
+	stdcontext "context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthurlch/goryu/app"
+	"github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/middleware/cors"
+	healthcheck "github.com/arthurlch/goryu/middleware/healthcheck"
+	"github.com/arthurlch/goryu/router"
+)
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestApp_NotFoundRunsMiddleware(t *testing.T) {
+	a := app.New()
+	a.Use(func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Writer.Header().Set("X-Request-ID", "abc")
+			next(c)
+		}
+	})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "home")
+	})
+
+	rr := serve(a, "GET", "/missing")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr.Header().Get("X-Request-ID") != "abc" {
+		t.Error("expected middleware to run for default 404")
+	}
+	if body := rr.Body.String(); body != "{\"error\":\"Not Found\"}\n" {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	a.NotFound(func(c *context.Context) {
+		_ = c.JSON(http.StatusNotFound, map[string]string{"message": "nothing here"})
+	})
+	a.MethodNotAllowed(func(c *context.Context) {
+		_ = c.Text(http.StatusMethodNotAllowed, "nope")
+	})
+
+	rr = serve(a, "GET", "/missing")
+	if body := rr.Body.String(); body != "{\"message\":\"nothing here\"}\n" {
+		t.Errorf("expected custom NotFound body, got: %s", body)
+	}
+	if rr.Header().Get("X-Request-ID") != "abc" {
+		t.Error("expected middleware to run for custom 404")
+	}
+
+	rr = serve(a, "POST", "/")
+	if rr.Code != http.StatusMethodNotAllowed || rr.Body.String() != "nope" {
+		t.Errorf("expected custom 405, got %d '%s'", rr.Code, rr.Body.String())
+	}
+}
+
+func TestApp_PreflightRunsMiddleware(t *testing.T) {
+	a := app.New()
+	a.Use(cors.New())
+	a.GET("/items", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "items")
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/items", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rr := httptest.NewRecorder()
+	a.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://example.com" || rr.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("expected CORS headers on the automatic OPTIONS response, got %v", rr.Header())
+	}
+	if rr.Header().Get("Allow") == "" {
+		t.Error("expected the Allow header")
+	}
+}
+
+func TestApp_MountedNotFound(t *testing.T) {
+	parent := app.New()
+	sub := app.New()
+	sub.GET("/ping", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "pong")
+	})
+	sub.NotFound(func(c *context.Context) {
+		_ = c.Text(http.StatusNotFound, "sub not found")
+	})
+	parent.Mount("/sub", sub)
+
+	rr := serve(parent, "GET", "/sub/ping")
+	if rr.Body.String() != "pong" {
+		t.Errorf("expected 'pong', got '%s'", rr.Body.String())
+	}
+
+	rr = serve(parent, "GET", "/sub/missing")
+	if rr.Code != http.StatusNotFound || rr.Body.String() != "sub not found" {
+		t.Errorf("expected sub-app NotFound handler, got %d '%s'", rr.Code, rr.Body.String())
+	}
+}
+
+func TestApp_Static(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "css", "site.css"), []byte("body{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := app.New()
+	a.Static("/assets", dir)
+
+	rr := serve(a, "GET", "/assets/css/site.css")
+	if rr.Code != http.StatusOK || rr.Body.String() != "body{}" {
+		t.Errorf("expected file contents, got %d '%s'", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(a, "GET", "/assets/css/")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "site.css") {
+		t.Errorf("expected directory listing, got %d '%s'", rr.Code, rr.Body.String())
+	}
+}
+
+func TestApp_MountedReverse(t *testing.T) {
+	parent := app.New()
+	sub := app.New()
+	sub.GET("/users/:id", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.URLFor("user", c.Param("id")))
+	}).SetName("user")
+	parent.Mount("/v1", sub)
+
+	if got := parent.Router.Reverse("user", 3); got != "/v1/users/3" {
+		t.Errorf("expected '/v1/users/3', got '%s'", got)
+	}
+	if rr := serve(parent, "GET", "/v1/users/9"); rr.Body.String() != "/v1/users/9" {
+		t.Errorf("expected URLFor to include the mount prefix, got '%s'", rr.Body.String())
+	}
+}
+
+func TestApp_UseAppliesRegardlessOfOrder(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := app.New()
+	a.GET("/early", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "early")
+	})
+	a.Group("/g").GET("/route", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "group")
+	})
+	a.Static("/files", dir)
+
+	a.Use(func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Writer.Header().Set("X-App", "1")
+			next(c)
+		}
+	})
+
+	for _, path := range []string{"/early", "/g/route", "/files/a.txt", "/missing"} {
+		if rr := serve(a, "GET", path); rr.Header().Get("X-App") != "1" {
+			t.Errorf("%s: expected app middleware to run", path)
+		}
+	}
+}
+
+func TestApp_UseHTTP(t *testing.T) {
+	a := app.New()
+	a.UseHTTP(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Frame-Options", "DENY")
+			next.ServeHTTP(w, r)
+		})
+	})
+	a.Use(func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Set("user", "alice")
+			next(c)
+		}
+	})
+	a.GET("/users/:id", context.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := context.FromRequest(r)
+		user, _ := c.Get("user")
+		_, _ = w.Write([]byte(user.(string) + " " + c.Param("id")))
+	})))
+
+	rr := serve(a, "GET", "/users/5")
+	if rr.Header().Get("X-Frame-Options") != "DENY" {
+		t.Error("expected net/http middleware to run")
+	}
+	if rr.Body.String() != "alice 5" {
+		t.Errorf("expected 'alice 5', got '%s'", rr.Body.String())
+	}
+}
+
+func TestApp_Hooks(t *testing.T) {
+	a := app.New(app.Config{DisableStartupMessage: true})
+	var events []string
+	a.Hooks().OnRoute(func(r *router.Route) error {
+		events = append(events, "route "+r.Method+" "+r.Path)
+		return nil
+	})
+	a.Hooks().OnGroup(func(g *router.Group) error {
+		events = append(events, "group "+g.Prefix())
+		return nil
+	})
+
+	a.GET("/", func(c *context.Context) {})
+	a.Group("/api").POST("/users", func(c *context.Context) {})
+
+	sub := app.New()
+	sub.Hooks().OnMount(func(parent *app.App, prefix string) error {
+		if parent != a {
+			t.Error("expected OnMount to receive the parent app")
+		}
+		events = append(events, "mount "+prefix)
+		return nil
+	})
+	a.Mount("/sub", sub)
+
+	want := []string{"route GET /", "group /api", "route POST /api/users", "mount /sub", "route ALL /sub/*subpath"}
+	if strings.Join(events, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected events %v, got %v", want, events)
+	}
+
+	a.Hooks().OnRoute(func(r *router.Route) error {
+		return errors.New("routes are frozen")
+	})
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an OnRoute error to panic")
+		}
+	}()
+	a.GET("/late", func(c *context.Context) {})
+}
+
+func TestApp_ListenAndShutdownHooks(t *testing.T) {
+	a := app.New(app.Config{DisableStartupMessage: true})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "ok")
+	})
+
+	listening := make(chan app.ListenData, 1)
+	var events []string
+	a.Hooks().OnListen(func(data app.ListenData) error {
+		listening <- data
+		return nil
+	})
+	a.Hooks().OnPreShutdown(func() error {
+		events = append(events, "pre")
+		return errors.New("flush failed")
+	}, func() error {
+		events = append(events, "pre2")
+		return nil
+	})
+	a.Hooks().OnShutdown(func() error {
+		events = append(events, "post")
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- a.Listen("127.0.0.1:0") }()
+	data := <-listening
+	if data.Network != "tcp" || data.Addr == "" {
+		t.Fatalf("unexpected listen data %+v", data)
+	}
+
+	resp, err := http.Get("http://" + data.Addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	err = a.Shutdown()
+	if err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Errorf("expected the pre-shutdown error, got %v", err)
+	}
+	if got := strings.Join(events, ","); got != "pre,pre2,post" {
+		t.Errorf("expected hooks 'pre,pre2,post', got '%s'", got)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("expected ErrServerClosed, got %v", err)
+	}
+
+	failing := app.New(app.Config{DisableStartupMessage: true})
+	failing.Hooks().OnListen(func(app.ListenData) error {
+		return errors.New("registry unavailable")
+	})
+	if err := failing.Listen("127.0.0.1:0"); err == nil || err.Error() != "registry unavailable" {
+		t.Errorf("expected the OnListen error, got %v", err)
+	}
+}
+
+func TestApp_ListenWithGracefulShutdown(t *testing.T) {
+	health := healthcheck.NewHealthChecker()
+	a := app.New(app.Config{DisableStartupMessage: true})
+	a.UseHTTP(health.Middleware)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	a.GET("/slow", func(c *context.Context) {
+		close(started)
+		<-release
+		_ = c.Text(http.StatusOK, "done")
+	})
+
+	listening := make(chan string, 1)
+	a.Hooks().OnListen(func(data app.ListenData) error {
+		listening <- data.Addr
+		return nil
+	})
+	shutdownHook := make(chan struct{})
+	a.Hooks().OnShutdown(func() error {
+		close(shutdownHook)
+		return nil
+	})
+
+	stop, trigger := stdcontext.WithCancel(stdcontext.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.ListenWithGracefulShutdown("127.0.0.1:0", app.GracefulShutdown{
+			Context:      stop,
+			Readiness:    health,
+			PreStopDelay: 50 * time.Millisecond,
+			DrainTimeout: 100 * time.Millisecond,
+		})
+	}()
+	addr := <-listening
+
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	trigger()
+
+	time.Sleep(20 * time.Millisecond)
+	resp, err := http.Get("http://" + addr + "/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness DOWN during the pre-stop delay, got %d", resp.StatusCode)
+	}
+
+	err = <-done
+	close(release)
+	var forceClosed *app.ForceClosedError
+	if !errors.As(err, &forceClosed) || forceClosed.Requests != 1 {
+		t.Fatalf("expected 1 force-closed request, got %v", err)
+	}
+	if !errors.Is(err, stdcontext.DeadlineExceeded) {
+		t.Errorf("expected the drain deadline error, got %v", err)
+	}
+	select {
+	case <-shutdownHook:
+	default:
+		t.Error("expected the OnShutdown hooks to run")
+	}
+}
+
+func TestApp_ServerConfig(t *testing.T) {
+	a := app.New(app.Config{WriteTimeout: 5 * time.Second, IdleTimeout: -1, DisableStartupMessage: true})
+	if a.Config.ReadHeaderTimeout != app.DefaultReadHeaderTimeout || a.Config.BodyLimit != app.DefaultBodyLimit {
+		t.Errorf("expected defaults to be filled, got %+v", a.Config)
+	}
+
+	listening := make(chan struct{})
+	a.Hooks().OnListen(func(app.ListenData) error {
+		close(listening)
+		return nil
+	})
+	go func() { _ = a.Listen("127.0.0.1:0") }()
+	<-listening
+	defer a.Shutdown()
+
+	server := a.Server()
+	if server.ReadTimeout != app.DefaultReadTimeout || server.WriteTimeout != 5*time.Second || server.IdleTimeout != 0 {
+		t.Errorf("unexpected server timeouts: read %s, write %s, idle %s", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+	if server.MaxHeaderBytes != app.DefaultMaxHeaderBytes {
+		t.Errorf("expected MaxHeaderBytes %d, got %d", app.DefaultMaxHeaderBytes, server.MaxHeaderBytes)
+	}
+
+	short := app.New(app.Config{ReadTimeout: 5 * time.Second})
+	if short.Config.ReadHeaderTimeout != 5*time.Second {
+		t.Errorf("expected the default ReadHeaderTimeout to be clamped to ReadTimeout, got %s", short.Config.ReadHeaderTimeout)
+	}
+
+	for _, cfg := range []app.Config{
+		{MaxHeaderBytes: -1},
+		{ReadTimeout: time.Second, ReadHeaderTimeout: 2 * time.Second},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected New to reject %+v", cfg)
+				}
+			}()
+			app.New(cfg)
+		}()
+	}
+}
+
+func TestApp_BodyLimit(t *testing.T) {
+	a := app.New(app.Config{BodyLimit: 8})
+	a.Use(func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Writer.Header().Set("X-Request-ID", "abc")
+			next(c)
+		}
+	})
+	a.POST("/echo", func(c *context.Context) {
+		var body map[string]string
+		if err := c.BindJSON(&body); err != nil {
+			c.Error(err)
+			return
+		}
+		_ = c.Text(http.StatusOK, "ok")
+	})
+
+	post := func(body string, chunked bool) int {
+		req := httptest.NewRequest("POST", "/echo", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if chunked {
+			req.ContentLength = -1
+		}
+		rr := httptest.NewRecorder()
+		a.ServeHTTP(rr, req)
+		if rr.Header().Get("X-Request-ID") != "abc" {
+			t.Errorf("expected middleware to run for a %d response", rr.Code)
+		}
+		return rr.Code
+	}
+
+	if code := post(`{}`, false); code != http.StatusOK {
+		t.Errorf("expected small body to pass, got %d", code)
+	}
+	if code := post(`{"name":"goryu"}`, false); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 from Content-Length, got %d", code)
+	}
+	if code := post(`{"name":"goryu"}`, true); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 while reading, got %d", code)
+	}
+}
+
+func TestApp_ListenUnix(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "goryu.sock")
+	// A stale socket from a previous run is replaced.
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	a := app.New(app.Config{DisableStartupMessage: true})
+	a.GET("/ping", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "pong")
+	})
+	listening := make(chan app.ListenData, 1)
+	a.Hooks().OnListen(func(data app.ListenData) error {
+		listening <- data
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- a.ListenUnix(socket, 0o600) }()
+	if data := <-listening; data.Network != "unix" || data.Addr != socket {
+		t.Errorf("unexpected listen data %+v", data)
+	}
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected socket mode 0600, got %o", info.Mode().Perm())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx stdcontext.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Errorf("expected 'pong', got '%s'", body)
+	}
+
+	if err := a.Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("expected ErrServerClosed, got %v", err)
+	}
+}
+
+func TestApp_Serve(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := app.New(app.Config{DisableStartupMessage: true})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "served")
+	})
+	listening := make(chan struct{})
+	a.Hooks().OnListen(func(app.ListenData) error {
+		close(listening)
+		return nil
+	})
+	go func() { _ = a.Serve(ln) }()
+	<-listening
+	defer a.Shutdown()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "served" {
+		t.Errorf("expected 'served', got '%s'", body)
+	}
+}
+
+func TestApp_ListenSystemdWithoutSockets(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	if err := app.New().ListenSystemd(); err == nil {
+		t.Error("expected an error for sockets passed to another process")
+	}
+}
+
+func TestApp_TrustedProxies(t *testing.T) {
+	a := app.New(app.Config{TrustedProxies: []string{"192.0.2.1"}})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.RemoteIP()+" "+c.BaseURL())
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rr := httptest.NewRecorder()
+	a.ServeHTTP(rr, req)
+	if rr.Body.String() != "203.0.113.5 https://example.com" {
+		t.Errorf("expected forwarded values from a trusted proxy, got '%s'", rr.Body.String())
+	}
+
+	req.RemoteAddr = "198.51.100.1:1234"
+	rr = httptest.NewRecorder()
+	a.ServeHTTP(rr, req)
+	if rr.Body.String() != "198.51.100.1 http://example.com" {
+		t.Errorf("expected headers from an untrusted peer to be ignored, got '%s'", rr.Body.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ProxyHeader without TrustedProxies to be rejected")
+		}
+	}()
+	app.New(app.Config{ProxyHeader: "X-Real-IP"})
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/arthurlch/goryu/router"
+)
+
+// ListenData describes the address the app is listening on.
+type ListenData struct {
+	Network string
+	Addr    string
+	TLS     bool
+}
+
+type (
+	OnRouteHandler    = func(route *router.Route) error
+	OnGroupHandler    = func(group *router.Group) error
+	OnListenHandler   = func(data ListenData) error
+	OnShutdownHandler = func() error
+	OnMountHandler    = func(parent *App, prefix string) error
+)
+
+// Hooks runs code at points of the app's lifecycle. Hooks of each kind run
+// in registration order.
+type Hooks struct {
+	mu             sync.Mutex
+	onRoute        []OnRouteHandler
+	onGroup        []OnGroupHandler
+	onListen       []OnListenHandler
+	onPreShutdown  []OnShutdownHandler
+	onPostShutdown []OnShutdownHandler
+	onMount        []OnMountHandler
+}
+
+func newHooks(app *App) *Hooks {
+	hooks := &Hooks{}
+	app.Router.OnRoute = func(route *router.Route) {
+		if err := hooks.executeOnRoute(route); err != nil {
+			panic(fmt.Sprintf("app: OnRoute hook for %s %s: %v", route.Method, route.Path, err))
+		}
+	}
+	app.Router.OnGroup = func(group *router.Group) {
+		if err := hooks.executeOnGroup(group); err != nil {
+			panic(fmt.Sprintf("app: OnGroup hook for %s: %v", group.Prefix(), err))
+		}
+	}
+	return hooks
+}
+
+// Hooks returns the lifecycle hooks of the app.
+func (app *App) Hooks() *Hooks {
+	return app.hooks
+}
+
+// OnRoute adds hooks called after a route is registered, including the
+// routes of groups, Mount and Static. A hook error panics, like a route
+// conflict.
+func (h *Hooks) OnRoute(handlers ...OnRouteHandler) {
+	h.mu.Lock()
+	h.onRoute = append(h.onRoute, handlers...)
+	h.mu.Unlock()
+}
+
+// OnGroup adds hooks called after a group is created. A hook error panics.
+func (h *Hooks) OnGroup(handlers ...OnGroupHandler) {
+	h.mu.Lock()
+	h.onGroup = append(h.onGroup, handlers...)
+	h.mu.Unlock()
+}
+
+// OnListen adds hooks called once the listener is open, before requests
+// are served. A hook error closes the listener and is returned by Listen.
+func (h *Hooks) OnListen(handlers ...OnListenHandler) {
+	h.mu.Lock()
+	h.onListen = append(h.onListen, handlers...)
+	h.mu.Unlock()
+}
+
+// OnPreShutdown adds hooks called before the server stops accepting
+// connections. Every hook runs; their errors are returned by Shutdown.
+func (h *Hooks) OnPreShutdown(handlers ...OnShutdownHandler) {
+	h.mu.Lock()
+	h.onPreShutdown = append(h.onPreShutdown, handlers...)
+	h.mu.Unlock()
+}
+
+// OnShutdown adds hooks called after the server has shut down, to release
+// resources used by handlers. Every hook runs; their errors are returned by
+// Shutdown.
+func (h *Hooks) OnShutdown(handlers ...OnShutdownHandler) {
+	h.mu.Lock()
+	h.onPostShutdown = append(h.onPostShutdown, handlers...)
+	h.mu.Unlock()
+}
+
+// OnMount adds hooks called when the app is mounted under a parent app,
+// before its routes are attached. A hook error panics.
+func (h *Hooks) OnMount(handlers ...OnMountHandler) {
+	h.mu.Lock()
+	h.onMount = append(h.onMount, handlers...)
+	h.mu.Unlock()
+}
+
+func (h *Hooks) executeOnRoute(route *router.Route) error {
+	h.mu.Lock()
+	handlers := h.onRoute
+	h.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler(route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) executeOnGroup(group *router.Group) error {
+	h.mu.Lock()
+	handlers := h.onGroup
+	h.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) executeOnListen(data ListenData) error {
+	h.mu.Lock()
+	handlers := h.onListen
+	h.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) executeOnMount(parent *App, prefix string) error {
+	h.mu.Lock()
+	handlers := h.onMount
+	h.mu.Unlock()
+	for _, handler := range handlers {
+		if err := handler(parent, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeOnShutdown runs every pre- or post-shutdown hook and joins their
+// errors.
+func (h *Hooks) executeOnShutdown(pre bool) error {
+	h.mu.Lock()
+	handlers := h.onPostShutdown
+	if pre {
+		handlers = h.onPreShutdown
+	}
+	h.mu.Unlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd.
+const listenFDsStart = 3
+
+// Serve serves requests on ln until the app is shut down.
+func (app *App) Serve(ln net.Listener) error {
+	return app.serveListeners([]net.Listener{ln}, nil)
+}
+
+// ListenUnix serves requests on the Unix domain socket at path, replacing a
+// stale socket left by a previous run. mode sets the socket's permissions
+// unless it is 0.
+func (app *App) ListenUnix(path string, mode os.FileMode) error {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+	return app.Serve(ln)
+}
+
+// ListenSystemd serves requests on the sockets passed by systemd socket
+// activation through LISTEN_PID and LISTEN_FDS.
+func (app *App) ListenSystemd() error {
+	lns, err := systemdListeners()
+	if err != nil {
+		return err
+	}
+	return app.serveListeners(lns, nil)
+}
+
+func systemdListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, fmt.Errorf("no sockets passed by systemd to this process")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	lns := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		ln, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, ln := range lns {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("systemd socket %d: %v", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// serveListeners serves requests on every listener with a single server,
+// so Shutdown stops them all, over TLS when tlsConfig is set. It returns the
+// first error.
+func (app *App) serveListeners(lns []net.Listener, tlsConfig *tls.Config) error {
+	server := app.newServer(lns[0].Addr().String())
+	server.TLSConfig = tlsConfig
+	errs := make(chan error, len(lns))
+	for _, ln := range lns {
+		ln = app.wrapListener(ln)
+		data := ListenData{Network: ln.Addr().Network(), Addr: ln.Addr().String(), TLS: tlsConfig != nil}
+		go func(ln net.Listener) {
+			errs <- app.serve(server, ln, data)
+		}(ln)
+	}
+
+	err := <-errs
+	if !errors.Is(err, http.ErrServerClosed) {
+		server.Close()
+	}
+	for i := 1; i < len(lns); i++ {
+		<-errs
+	}
+	return err
+}
+
+// wrapListener adds the PROXY protocol support configured for the app.
+func (app *App) wrapListener(ln net.Listener) net.Listener {
+	if app.Config.ProxyProtocol {
+		return &proxyListener{Listener: ln, trusted: app.proxyTrusted, timeout: timeout(app.Config.ReadHeaderTimeout)}
+	}
+	return ln
+}
@@ -0,0 +1,9 @@
+package app
+
+import "github.com/arthurlch/goryu/openapi"
+
+// OpenAPI serves an OpenAPI 3.1 document of the app's routes, and an HTML
+// viewer of it that works offline. See openapi.Config for the paths.
+func (app *App) OpenAPI(config ...openapi.Config) {
+	openapi.Register(app.Router, config...)
+}
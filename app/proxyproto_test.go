@@ -0,0 +1,124 @@
+package app_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/arthurlch/goryu/app"
+	"github.com/arthurlch/goryu/context"
+)
+
+// startProxyProtocol serves an app answering with the client IP and
+// returns its address.
+func startProxyProtocol(t *testing.T, trusted ...string) string {
+	t.Helper()
+	a := app.New(app.Config{
+		DisableStartupMessage:     true,
+		ProxyProtocol:             true,
+		ProxyProtocolTrustedCIDRs: trusted,
+	})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.RemoteIP())
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listening := make(chan struct{})
+	a.Hooks().OnListen(func(app.ListenData) error {
+		close(listening)
+		return nil
+	})
+	go func() { _ = a.Serve(ln) }()
+	<-listening
+	t.Cleanup(func() { _ = a.Shutdown() })
+	return ln.Addr().String()
+}
+
+// rawRequest sends header followed by a GET request and returns the
+// response.
+func rawRequest(t *testing.T, addr string, header []byte) (int, string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	request := append(header, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n"...)
+	if _, err := conn.Write(request); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, string(body)
+}
+
+func proxyV2Header(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	header := []byte("\r\n\r\n\x00\r\nQUIT\n")
+	header = append(header, 0x21, 0x11, 0, 12)
+	header = append(header, src.To4()...)
+	header = append(header, dst.To4()...)
+	header = binary.BigEndian.AppendUint16(header, srcPort)
+	return binary.BigEndian.AppendUint16(header, dstPort)
+}
+
+func TestApp_ProxyProtocol(t *testing.T) {
+	addr := startProxyProtocol(t, "127.0.0.1/32")
+
+	tests := []struct {
+		name   string
+		header []byte
+		want   string
+	}{
+		{"v1", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n"), "203.0.113.7"},
+		{"v1 ipv6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 51234 80\r\n"), "2001:db8::1"},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), "127.0.0.1"},
+		{"v2", proxyV2Header(net.ParseIP("198.51.100.9"), net.ParseIP("10.0.0.1"), 40000, 443), "198.51.100.9"},
+		{"no header", nil, "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := rawRequest(t, addr, tt.header)
+			if code != http.StatusOK || body != tt.want {
+				t.Errorf("expected 200 '%s', got %d '%s'", tt.want, code, body)
+			}
+		})
+	}
+}
+
+func TestApp_ProxyProtocolUntrusted(t *testing.T) {
+	addr := startProxyProtocol(t, "10.0.0.0/8")
+
+	// The header from an untrusted peer is not parsed, so the request line
+	// is invalid.
+	code, _ := rawRequest(t, addr, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\n"))
+	if code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a header from an untrusted peer, got %d", code)
+	}
+	if _, body := rawRequest(t, addr, nil); body != "127.0.0.1" {
+		t.Errorf("expected the peer address, got '%s'", body)
+	}
+}
+
+func TestApp_ProxyProtocolConfig(t *testing.T) {
+	for _, cidrs := range [][]string{nil, {"not-an-ip"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected New to reject trusted CIDRs %v", cidrs)
+				}
+			}()
+			app.New(app.Config{ProxyProtocol: true, ProxyProtocolTrustedCIDRs: cidrs})
+		}()
+	}
+}
@@ -1,79 +1,101 @@
 package app
 
 import (
-	"strings"
+	"fmt"
+	"net/http"
 
 	"github.com/arthurlch/goryu/context"
 	"github.com/arthurlch/goryu/router"
 )
 
+// Use adds middleware to every route of the app, including routes registered
+// before the call, groups, static files, mounted apps and the NotFound and
+// MethodNotAllowed handlers.
 func (app *App) Use(middleware context.Middleware) {
-	app.middlewares = append(app.middlewares, middleware)
+	app.Router.Use(middleware)
 }
 
-func (app *App) applyMiddleware(handler context.HandlerFunc) context.HandlerFunc {
-	for i := len(app.middlewares) - 1; i >= 0; i-- {
-		handler = app.middlewares[i](handler)
+// UseHTTP adds net/http middleware to every route of the app. Handlers
+// after it can still reach the Context from the request through
+// context.FromRequest.
+func (app *App) UseHTTP(middlewares ...func(http.Handler) http.Handler) {
+	for _, middleware := range middlewares {
+		app.Router.Use(context.WrapHTTPMiddleware(middleware))
 	}
-	return handler
+}
+
+// UseHandler adds chain handlers to every route of the app, like Use. They
+// continue the chain by calling Context.Next and stop it with Context.Abort.
+func (app *App) UseHandler(handlers ...context.HandlerFunc) {
+	app.Router.UseHandler(handlers...)
 }
 
 func (app *App) GET(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.GET(path, app.applyMiddleware(handler))
+	return app.Router.GET(path, handler)
 }
 
 func (app *App) POST(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.POST(path, app.applyMiddleware(handler))
+	return app.Router.POST(path, handler)
 }
 
 func (app *App) PUT(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.PUT(path, app.applyMiddleware(handler))
+	return app.Router.PUT(path, handler)
 }
 
 func (app *App) DELETE(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.DELETE(path, app.applyMiddleware(handler))
+	return app.Router.DELETE(path, handler)
 }
 
 func (app *App) PATCH(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.PATCH(path, app.applyMiddleware(handler))
+	return app.Router.PATCH(path, handler)
 }
 
 func (app *App) HEAD(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.HEAD(path, app.applyMiddleware(handler))
+	return app.Router.HEAD(path, handler)
 }
 
 func (app *App) OPTIONS(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.OPTIONS(path, app.applyMiddleware(handler))
+	return app.Router.OPTIONS(path, handler)
 }
 
 func (app *App) Group(prefix string, middlewares ...context.Middleware) *router.Group {
 	return app.Router.Group(prefix, middlewares...)
 }
-func (app *App) All(path string, handler context.HandlerFunc) *router.Route {
-	return app.Router.ALL(path, app.applyMiddleware(handler))
-}
 
-func (app *App) Mount(prefix string, subApp *App) {
-	subApp.mountPath = app.mountPath + prefix
-
-	mountHandler := func(c *context.Context) {
-		originalPath := c.Request.URL.Path
-		c.Request.URL.Path = strings.TrimPrefix(originalPath, prefix)
+// Route creates a group for prefix and passes it to fn.
+func (app *App) Route(prefix string, fn func(g *router.Group), middlewares ...context.Middleware) *router.Group {
+	group := app.Router.Group(prefix, middlewares...)
+	fn(group)
+	return group
+}
 
-		if c.Request.URL.Path == "" {
-			c.Request.URL.Path = "/"
-		}
+func (app *App) All(path string, handler context.HandlerFunc) *router.Route {
+	return app.Router.ALL(path, handler)
+}
 
-		subApp.ServeHTTP(c.Writer, c.Request)
+// NotFound sets the handler for requests that match no route. Like routes, it
+// runs through the app's middleware.
+func (app *App) NotFound(handler context.HandlerFunc) {
+	app.Router.NotFound = handler
+}
 
-		c.Request.URL.Path = originalPath
-	}
+// MethodNotAllowed sets the handler for requests whose path only exists under
+// other methods. It runs through the app's middleware.
+func (app *App) MethodNotAllowed(handler context.HandlerFunc) {
+	app.Router.MethodNotAllowed = handler
+}
 
-	routePath := prefix
-	if !strings.HasSuffix(routePath, "/") {
-		routePath += "/"
+// Mount serves subApp under prefix. Requests reach it through this app's
+// middleware, with the prefix stripped from the URL path. The OnMount hooks
+// of subApp run first; an error from one of them panics.
+func (app *App) Mount(prefix string, subApp *App) {
+	if err := subApp.hooks.executeOnMount(app, prefix); err != nil {
+		panic(fmt.Sprintf("app: OnMount hook for %s: %v", prefix, err))
 	}
-	routePath += "*subpath"
+	app.Router.Mount(prefix, subApp)
+}
 
-	app.All(routePath, mountHandler)
+// Mounted implements router.Mountable.
+func (app *App) Mounted(parent *router.Router, prefix string) {
+	app.Router.Mounted(parent, prefix)
 }
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Readiness is implemented by health checkers whose readiness can be turned
+// off while the app shuts down, like healthcheck.HealthChecker.
+type Readiness interface {
+	SetReady(ready bool)
+}
+
+type GracefulShutdown struct {
+	// Signals start the shutdown.
+	// Default: SIGINT, SIGTERM
+	Signals []os.Signal
+	// Context also starts the shutdown when it is done.
+	// Default: nil
+	Context context.Context
+	// Readiness is set to DOWN as soon as the shutdown starts.
+	// Default: nil
+	Readiness Readiness
+	// PreStopDelay is the time to wait after the readiness flip, for load
+	// balancers to stop sending new requests.
+	// Default: 0
+	PreStopDelay time.Duration
+	// DrainTimeout is how long in-flight requests get to finish before
+	// their connections are closed.
+	// Default: 15 * time.Second
+	DrainTimeout time.Duration
+}
+
+// ForceClosedError is returned by ListenWithGracefulShutdown when requests
+// were still running at the end of the drain timeout.
+type ForceClosedError struct {
+	// Requests is the number of requests whose connections were closed.
+	Requests int
+	Err      error
+}
+
+func (e *ForceClosedError) Error() string {
+	return fmt.Sprintf("%d requests force-closed after the drain timeout: %v", e.Requests, e.Err)
+}
+
+func (e *ForceClosedError) Unwrap() error {
+	return e.Err
+}
+
+// ListenWithGracefulShutdown listens on addr like Listen and shuts down
+// gracefully on SIGINT or SIGTERM: readiness is flipped to DOWN, the
+// pre-stop delay elapses, in-flight requests are drained and the shutdown
+// hooks run. It returns nil after a clean shutdown.
+func (app *App) ListenWithGracefulShutdown(addr string, opts ...GracefulShutdown) error {
+	cfg := GracefulShutdown{}
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if len(cfg.Signals) == 0 {
+		cfg.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 15 * time.Second
+	}
+	var done <-chan struct{}
+	if cfg.Context != nil {
+		done = cfg.Context.Done()
+	}
+
+	if addr == "" {
+		addr = ":http"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, cfg.Signals...)
+	defer signal.Stop(signals)
+
+	data := ListenData{Network: "tcp", Addr: ln.Addr().String()}
+	server := app.newServer(data.Addr)
+	served := make(chan error, 1)
+	go func() {
+		served <- app.serve(server, app.wrapListener(ln), data)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-signals:
+	case <-done:
+	}
+
+	if cfg.Readiness != nil {
+		cfg.Readiness.SetReady(false)
+	}
+	if cfg.PreStopDelay > 0 {
+		time.Sleep(cfg.PreStopDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	forced, err := app.shutdown(ctx, true)
+	if serveErr := <-served; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(err, serveErr)
+	}
+	if forced > 0 {
+		return &ForceClosedError{Requests: forced, Err: err}
+	}
+	return err
+}
@@ -1,11 +1,7 @@
 package app
 
 import (
-	"net/http"
-	"strings"
 	"time"
-
-	"github.com/arthurlch/goryu/context"
 )
 
 type Static struct {
@@ -16,17 +12,5 @@ type Static struct {
 }
 
 func (app *App) Static(prefix, root string, config ...Static) {
-	fs := http.FileServer(http.Dir(root))
-
-	handler := func(c *context.Context) {
-		http.StripPrefix(prefix, fs).ServeHTTP(c.Writer, c.Request)
-	}
-
-	routePath := prefix
-	if !strings.HasSuffix(routePath, "/") {
-		routePath += "/"
-	}
-	routePath += "*filepath"
-
-	app.Router.GET(routePath, handler)
+	app.Router.Static(prefix, root)
 }
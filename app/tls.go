@@ -0,0 +1,213 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertKeyPair names the PEM files of a certificate and its private key.
+type CertKeyPair struct {
+	CertFile string
+	KeyFile  string
+}
+
+type TLSConfig struct {
+	// Certificates are served by SNI: the first one whose names match the
+	// requested server name, or the first one when none does.
+	Certificates []CertKeyPair
+	// ClientCAs verifies client certificates, enabling mutual TLS.
+	// Default: nil
+	ClientCAs *x509.CertPool
+	// ClientAuth is the client certificate policy.
+	// Default: tls.RequireAndVerifyClientCert with ClientCAs, otherwise
+	// tls.NoClientCert
+	ClientAuth tls.ClientAuthType
+	// MinVersion is the minimum TLS version accepted.
+	// Default: tls.VersionTLS12
+	MinVersion uint16
+	// ReloadInterval is how often the certificate files are checked for
+	// changes. A negative value disables reloading.
+	// Default: 30 * time.Second
+	ReloadInterval time.Duration
+}
+
+// ListenTLS serves HTTPS on addr with the given certificate, reloading it
+// when the files change.
+func (app *App) ListenTLS(addr, certFile, keyFile string) error {
+	return app.ListenTLSWithConfig(addr, TLSConfig{
+		Certificates: []CertKeyPair{{CertFile: certFile, KeyFile: keyFile}},
+	})
+}
+
+// ListenMutualTLS serves HTTPS on addr and requires client certificates
+// signed by a CA from clientCAFile.
+func (app *App) ListenMutualTLS(addr, certFile, keyFile, clientCAFile string) error {
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	return app.ListenTLSWithConfig(addr, TLSConfig{
+		Certificates: []CertKeyPair{{CertFile: certFile, KeyFile: keyFile}},
+		ClientCAs:    pool,
+	})
+}
+
+// ListenTLSWithConfig serves HTTPS on addr as described by cfg.
+func (app *App) ListenTLSWithConfig(addr string, cfg TLSConfig) error {
+	store, err := LoadCertificates(cfg.Certificates...)
+	if err != nil {
+		return err
+	}
+	if cfg.ReloadInterval == 0 {
+		cfg.ReloadInterval = 30 * time.Second
+	}
+	if cfg.ReloadInterval > 0 {
+		stop := store.Watch(cfg.ReloadInterval)
+		defer stop()
+	}
+
+	tlsConfig := &tls.Config{
+		GetCertificate: store.GetCertificate,
+		ClientCAs:      cfg.ClientCAs,
+		ClientAuth:     cfg.ClientAuth,
+		MinVersion:     cfg.MinVersion,
+	}
+	if tlsConfig.ClientCAs != nil && tlsConfig.ClientAuth == tls.NoClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+
+	if addr == "" {
+		addr = ":https"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return app.serveListeners([]net.Listener{ln}, tlsConfig)
+}
+
+// CertStore holds certificates loaded from files, picks one per connection
+// by SNI and reloads them when the files change.
+type CertStore struct {
+	pairs []CertKeyPair
+
+	mu       sync.RWMutex
+	certs    []*tls.Certificate
+	modTimes []time.Time
+}
+
+// LoadCertificates loads the certificate pairs into a CertStore.
+func LoadCertificates(pairs ...CertKeyPair) (*CertStore, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("no certificates given")
+	}
+	store := &CertStore{pairs: pairs}
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Reload loads the certificate files again. The current certificates are
+// kept if one of them fails to load.
+func (s *CertStore) Reload() error {
+	// The times are read first: a renewal written while the files load
+	// then still shows as a change and is picked up by the next check.
+	modTimes := s.currentModTimes()
+	certs := make([]*tls.Certificate, 0, len(s.pairs))
+	for _, pair := range s.pairs {
+		cert, err := tls.LoadX509KeyPair(pair.CertFile, pair.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s: %v", pair.CertFile, err)
+		}
+		certs = append(certs, &cert)
+	}
+
+	s.mu.Lock()
+	s.certs = certs
+	s.modTimes = modTimes
+	s.mu.Unlock()
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if hello.ServerName != "" {
+		for _, cert := range s.certs {
+			if cert.Leaf != nil && cert.Leaf.VerifyHostname(hello.ServerName) == nil {
+				return cert, nil
+			}
+		}
+	}
+	return s.certs[0], nil
+}
+
+// Watch checks the certificate files every interval and reloads them when
+// they change. Call the returned function to stop watching.
+func (s *CertStore) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !s.changed() {
+					continue
+				}
+				if err := s.Reload(); err != nil {
+					log.Printf("[GORYU] certificate reload failed: %v", err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (s *CertStore) changed() bool {
+	current := s.currentModTimes()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := range current {
+		if !current[i].Equal(s.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentModTimes returns the modification times of the certificate and
+// key files, in pair order.
+func (s *CertStore) currentModTimes() []time.Time {
+	times := make([]time.Time, 0, 2*len(s.pairs))
+	for _, pair := range s.pairs {
+		for _, file := range []string{pair.CertFile, pair.KeyFile} {
+			var modTime time.Time
+			if info, err := os.Stat(file); err == nil {
+				modTime = info.ModTime()
+			}
+			times = append(times, modTime)
+		}
+	}
+	return times
+}
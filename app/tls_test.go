@@ -0,0 +1,166 @@
+package app_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arthurlch/goryu/app"
+	"github.com/arthurlch/goryu/context"
+)
+
+func generateCert(t *testing.T, name string, hosts ...string) app.CertKeyPair {
+	t.Helper()
+	dir := t.TempDir()
+	pair := app.CertKeyPair{
+		CertFile: filepath.Join(dir, name+".pem"),
+		KeyFile:  filepath.Join(dir, name+"-key.pem"),
+	}
+	if err := app.GenerateSelfSignedCert(pair.CertFile, pair.KeyFile, hosts...); err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func certPool(t *testing.T, certFile string) *x509.CertPool {
+	t.Helper()
+	pem, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(pem)
+	return pool
+}
+
+// startTLS runs listen on a new app and returns the app and its address.
+func startTLS(t *testing.T, listen func(a *app.App) error) (*app.App, string) {
+	t.Helper()
+	a := app.New(app.Config{DisableStartupMessage: true})
+	a.GET("/", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.Protocol())
+	})
+	listening := make(chan app.ListenData, 1)
+	a.Hooks().OnListen(func(data app.ListenData) error {
+		listening <- data
+		return nil
+	})
+	failed := make(chan error, 1)
+	go func() { failed <- listen(a) }()
+
+	select {
+	case data := <-listening:
+		if !data.TLS {
+			t.Error("expected ListenData.TLS to be set")
+		}
+		t.Cleanup(func() { _ = a.Shutdown() })
+		return a, data.Addr
+	case err := <-failed:
+		t.Fatalf("listen failed: %v", err)
+		return nil, ""
+	}
+}
+
+func TestApp_ListenTLS(t *testing.T) {
+	pair := generateCert(t, "server")
+	_, addr := startTLS(t, func(a *app.App) error {
+		return a.ListenTLS("127.0.0.1:0", pair.CertFile, pair.KeyFile)
+	})
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: certPool(t, pair.CertFile)},
+	}}
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "https" {
+		t.Errorf("expected 'https', got '%s'", body)
+	}
+}
+
+func TestApp_ListenMutualTLS(t *testing.T) {
+	server := generateCert(t, "server")
+	clientPair := generateCert(t, "client", "client")
+	_, addr := startTLS(t, func(a *app.App) error {
+		return a.ListenMutualTLS("127.0.0.1:0", server.CertFile, server.KeyFile, clientPair.CertFile)
+	})
+
+	clientCert, err := tls.LoadX509KeyPair(clientPair.CertFile, clientPair.KeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCAs := certPool(t, server.CertFile)
+
+	anonymous := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: rootCAs},
+	}}
+	if resp, err := anonymous.Get("https://" + addr + "/"); err == nil {
+		resp.Body.Close()
+		t.Error("expected a client without certificate to be rejected")
+	}
+
+	authenticated := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{clientCert}},
+	}}
+	resp, err := authenticated.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestCertStore_SNIAndReload(t *testing.T) {
+	a := generateCert(t, "a", "a.example.com")
+	b := generateCert(t, "b", "b.example.com")
+	store, err := app.LoadCertificates(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverName := func(name string) string {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert.Leaf.DNSNames[0]
+	}
+	if got := serverName("b.example.com"); got != "b.example.com" {
+		t.Errorf("expected the b.example.com certificate, got %s", got)
+	}
+	if got := serverName("unknown.example.com"); got != "a.example.com" {
+		t.Errorf("expected the default certificate, got %s", got)
+	}
+
+	stop := store.Watch(10 * time.Millisecond)
+	defer stop()
+	// Replace the default certificate and push its modification time
+	// forward, so the change is seen even on coarse filesystem clocks.
+	if err := app.GenerateSelfSignedCert(a.CertFile, a.KeyFile, "c.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{a.CertFile, a.KeyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serverName("") != "c.example.com" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the certificate to be reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,87 @@
+package context
+
+import (
+	stdcontext "context"
+	"net/http"
+)
+
+type contextKey struct{}
+
+// requestContext exposes a Context through http.Request.Context(): the
+// Context itself under FromRequest, and its Keys as string values.
+type requestContext struct {
+	stdcontext.Context
+	c *Context
+}
+
+func (rc *requestContext) Value(key interface{}) interface{} {
+	if _, ok := key.(contextKey); ok {
+		return rc.c
+	}
+	if k, ok := key.(string); ok {
+		if value, exists := rc.c.Keys[k]; exists {
+			return value
+		}
+	}
+	return rc.Context.Value(key)
+}
+
+// FromRequest returns the Context attached to a request that crossed from
+// goryu into net/http code, so plain handlers can reach its Keys and Params.
+func FromRequest(r *http.Request) (*Context, bool) {
+	c, ok := r.Context().Value(contextKey{}).(*Context)
+	return c, ok
+}
+
+// attachRequest makes c reachable from c.Request.Context() and returns the
+// request to hand to net/http code.
+func (c *Context) attachRequest() *http.Request {
+	if attached, ok := FromRequest(c.Request); ok && attached == c {
+		return c.Request
+	}
+	c.Request = c.Request.WithContext(&requestContext{Context: c.Request.Context(), c: c})
+	return c.Request
+}
+
+// WrapHandler adapts an http.Handler to a HandlerFunc.
+func WrapHandler(handler http.Handler) HandlerFunc {
+	return func(c *Context) {
+		handler.ServeHTTP(c.Writer, c.attachRequest())
+	}
+}
+
+// WrapHTTPMiddleware adapts a net/http middleware to a Middleware. The rest
+// of the chain sees the writer and request the middleware passes on; they
+// are restored once it returns.
+func WrapHTTPMiddleware(middleware func(http.Handler) http.Handler) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) {
+			writer, request := c.Writer, c.attachRequest()
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.Writer, c.Request = w, r
+				next(c)
+			}))
+			handler.ServeHTTP(writer, request)
+			c.Writer, c.Request = writer, request
+		}
+	}
+}
+
+// ToHTTPMiddleware adapts a Middleware to net/http. When the request already
+// carries a Context, it is reused so Keys and Params are preserved;
+// otherwise a new Context is created.
+func ToHTTPMiddleware(middleware Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := FromRequest(r)
+			if ok {
+				c.Writer, c.Request = w, r
+			} else {
+				c = NewContext(w, r)
+			}
+			middleware(func(c *Context) {
+				next.ServeHTTP(c.Writer, c.attachRequest())
+			})(c)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package context_test
+
+import (
+	stdcontext "context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthurlch/goryu/context"
+)
+
+type userKey struct{}
+
+func TestContext_WrapHTTPMiddleware(t *testing.T) {
+	httpMiddleware := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c, ok := context.FromRequest(r)
+			if !ok {
+				t.Fatal("expected the Context to be reachable from the request")
+			}
+			if c.Param("id") != "7" {
+				t.Errorf("expected param '7', got '%s'", c.Param("id"))
+			}
+			if trace := r.Context().Value("trace"); trace != "abc" {
+				t.Errorf("expected Context key through the request, got %v", trace)
+			}
+			w.Header().Set("X-HTTP", "1")
+			next.ServeHTTP(w, r.WithContext(stdcontext.WithValue(r.Context(), userKey{}, "alice")))
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, httptest.NewRequest("GET", "/users/7", nil))
+	c.Params = append(c.Params, context.Param{Key: "id", Value: "7"})
+	c.Set("trace", "abc")
+	original := c.Request
+
+	handler := context.WrapHTTPMiddleware(httpMiddleware)(func(c *context.Context) {
+		if user := c.Request.Context().Value(userKey{}); user != "alice" {
+			t.Errorf("expected the request passed on by the middleware, got %v", user)
+		}
+		_ = c.Text(http.StatusOK, c.Param("id"))
+	})
+	handler(c)
+
+	if rr.Header().Get("X-HTTP") != "1" || rr.Body.String() != "7" {
+		t.Errorf("expected middleware and handler to run, got '%s'", rr.Body.String())
+	}
+	if c.Request.Context().Value(userKey{}) != nil || c.Request.URL != original.URL {
+		t.Error("expected the request to be restored after the middleware returned")
+	}
+}
+
+func TestContext_ToHTTPMiddleware(t *testing.T) {
+	tag := func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Set("tag", "goryu")
+			next(c)
+		}
+	}
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := context.FromRequest(r)
+		if !ok {
+			t.Fatal("expected the Context to be reachable from the request")
+		}
+		tag, _ := c.Get("tag")
+		_, _ = w.Write([]byte(tag.(string) + " " + c.Param("id")))
+	})
+
+	rr := httptest.NewRecorder()
+	context.ToHTTPMiddleware(tag)(final).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Body.String() != "goryu " {
+		t.Errorf("expected 'goryu ', got '%s'", rr.Body.String())
+	}
+
+	// Round trip: goryu -> net/http -> goryu keeps the same Context.
+	rr = httptest.NewRecorder()
+	c := context.NewContext(rr, httptest.NewRequest("GET", "/", nil))
+	c.Params = append(c.Params, context.Param{Key: "id", Value: "3"})
+	context.WrapHandler(context.ToHTTPMiddleware(tag)(final))(c)
+	if rr.Body.String() != "goryu 3" {
+		t.Errorf("expected 'goryu 3', got '%s'", rr.Body.String())
+	}
+	if value, _ := c.Get("tag"); value != "goryu" {
+		t.Errorf("expected key set across the boundary on the original Context, got %v", value)
+	}
+}
@@ -0,0 +1,208 @@
+package context
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+// maxMultipartMemory is the part of a multipart body kept in memory; the
+// rest of the files is stored on disk.
+const maxMultipartMemory = 32 << 20
+
+// Bind decodes the request body into out, choosing the decoder from the
+// Content-Type: JSON, XML, URL-encoded or multipart forms. A request
+// without a body is bound from the query string. Like every binder, it then
+// runs the `validate` rules of the fields it bound (see Validate).
+//
+// Failures are returned as an *HTTPError: 400 for invalid values (wrapping a
+// *BindError when fields are at fault), 413 for bodies over the limit, 415
+// for unsupported content types and 422 for values failing validation
+// (wrapping a *ValidationError).
+func (c *Context) Bind(out interface{}) error {
+	if !c.hasBody() {
+		return c.BindQuery(out)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return c.bindBody(out, "json", json.NewDecoder(c.Request.Body).Decode)
+	case "application/xml", "text/xml":
+		return c.bindBody(out, "xml", xml.NewDecoder(c.Request.Body).Decode)
+	case "application/x-www-form-urlencoded":
+		return c.BindForm(out)
+	case "multipart/form-data":
+		return c.BindMultipart(out)
+	}
+	return NewHTTPError(http.StatusUnsupportedMediaType)
+}
+
+// BindXML decodes an XML request body into out.
+func (c *Context) BindXML(out interface{}) error {
+	return c.bindBody(out, "xml", xml.NewDecoder(c.Request.Body).Decode)
+}
+
+// BindQuery binds the query string to the fields of out tagged `query`.
+func (c *Context) BindQuery(out interface{}) error {
+	query := c.Request.URL.Query()
+	return c.bindValues(out, "query", func(key string) []string { return query[key] }, nil)
+}
+
+// BindForm binds URL-encoded or multipart form values to the fields of out
+// tagged `form`.
+func (c *Context) BindForm(out interface{}) error {
+	if err := c.parseForm(); err != nil {
+		return err
+	}
+	return c.bindValues(out, "form", c.formValues, nil)
+}
+
+// BindMultipart binds a multipart form to the fields of out tagged `form`,
+// including uploaded files in *multipart.FileHeader and
+// []*multipart.FileHeader fields.
+func (c *Context) BindMultipart(out interface{}) error {
+	if err := c.parseForm(); err != nil {
+		return err
+	}
+	if c.Request.MultipartForm == nil {
+		return NewHTTPError(http.StatusUnsupportedMediaType)
+	}
+	files := func(key string) []*multipart.FileHeader {
+		return c.Request.MultipartForm.File[key]
+	}
+	return c.bindValues(out, "form", c.formValues, files)
+}
+
+// BindHeader binds request headers to the fields of out tagged `header`.
+func (c *Context) BindHeader(out interface{}) error {
+	return c.bindValues(out, "header", c.Request.Header.Values, nil)
+}
+
+// BindCookie binds cookies to the fields of out tagged `cookie`.
+func (c *Context) BindCookie(out interface{}) error {
+	cookies := func(key string) []string {
+		var values []string
+		for _, cookie := range c.Request.CookiesNamed(key) {
+			values = append(values, cookie.Value)
+		}
+		return values
+	}
+	return c.bindValues(out, "cookie", cookies, nil)
+}
+
+// BindParams binds route parameters to the fields of out tagged `param`.
+func (c *Context) BindParams(out interface{}) error {
+	params := func(key string) []string {
+		if value, ok := c.Params.Get(key); ok {
+			return []string{value}
+		}
+		return nil
+	}
+	return c.bindValues(out, "param", params, nil)
+}
+
+// QueryParser binds the query string to the fields of out tagged `query`.
+// It is kept for compatibility with BindQuery.
+func (c *Context) QueryParser(out interface{}) error {
+	return c.BindQuery(out)
+}
+
+// hasBody reports whether the request declares a body.
+func (c *Context) hasBody() bool {
+	if c.GetHeader("Content-Type") != "" {
+		return true
+	}
+	return c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0
+}
+
+func (c *Context) bindValues(out interface{}, tag string, values func(string) []string, files func(string) []*multipart.FileHeader) error {
+	b := &binder{tag: tag, values: values, files: files}
+	if err := b.bind(out); err != nil {
+		var bindErr *BindError
+		if errors.As(err, &bindErr) {
+			return bindFailed(err)
+		}
+		return err
+	}
+	return validated(out, tag, func(field reflect.StructField) bool {
+		return tagName(field, tag) != ""
+	})
+}
+
+// bindBody applies the default tags, then decodes the body into out.
+func (c *Context) bindBody(out interface{}, tag string, decode func(interface{}) error) error {
+	if err := applyDefaults(out); err != nil {
+		return bindFailed(err)
+	}
+	if err := decode(out); err != nil {
+		return bindFailed(err)
+	}
+	return validated(out, tag, isBodyField)
+}
+
+// valueTags are the tags of the binders reading request values.
+var valueTags = []string{"query", "form", "header", "cookie", "param"}
+
+// isBodyField reports whether a field is decoded from the body, leaving out
+// those tagged only for another part of the request.
+func isBodyField(field reflect.StructField) bool {
+	for _, tag := range []string{"json", "xml"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return true
+		}
+	}
+	for _, tag := range valueTags {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validated runs the `validate` rules of the fields accepted by include,
+// naming them after tag. Failed rules give a 422 *HTTPError; mistakes in
+// the tags are returned as is, so they are served as a 500.
+func validated(out interface{}, tag string, include func(reflect.StructField) bool) error {
+	err := validate(out, tag, include)
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return NewHTTPError(http.StatusUnprocessableEntity, "validation failed").Wrap(err)
+	}
+	return err
+}
+
+func (c *Context) parseForm() error {
+	var err error
+	if c.Request.MultipartForm == nil {
+		err = c.Request.ParseMultipartForm(maxMultipartMemory)
+	}
+	if errors.Is(err, http.ErrNotMultipart) {
+		err = c.Request.ParseForm()
+	}
+	if err != nil {
+		return bindFailed(err)
+	}
+	return nil
+}
+
+func (c *Context) formValues(key string) []string {
+	return c.Request.Form[key]
+}
+
+// bindFailed maps a binding failure to the HTTP error sent to the client.
+func bindFailed(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return NewHTTPError(http.StatusRequestEntityTooLarge).Wrap(err)
+	}
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return NewHTTPError(http.StatusBadRequest, "invalid request").Wrap(err)
+	}
+	return NewHTTPError(http.StatusBadRequest, "invalid request body").Wrap(err)
+}
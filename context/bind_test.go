@@ -0,0 +1,321 @@
+package context_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthurlch/goryu/context"
+)
+
+type address struct {
+	City string `query:"city" form:"city" json:"city"`
+	Zip  string `query:"zip" form:"zip" json:"zip"`
+}
+
+type listParams struct {
+	Page     int           `query:"page" default:"1"`
+	Limit    uint8         `query:"limit" default:"20"`
+	Ratio    float64       `query:"ratio"`
+	Tags     []string      `query:"tag"`
+	IDs      []int         `query:"id"`
+	Since    time.Time     `query:"since"`
+	Timeout  time.Duration `query:"timeout"`
+	Debug    *bool         `query:"debug"`
+	Home     address       `query:"home"`
+	Work     *address      `query:"work"`
+	Ignored  string        `query:"-"`
+	internal string
+}
+
+func TestContext_BindQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?ratio=0.5&tag=a&tag=b&id=1&id=2&since=2024-05-01&timeout=3s&debug=on&home.city=Paris&Ignored=x", nil)
+	c := context.NewContext(httptest.NewRecorder(), req)
+
+	var p listParams
+	if err := c.BindQuery(&p); err != nil {
+		t.Fatalf("BindQuery failed: %v", err)
+	}
+
+	if p.Page != 1 || p.Limit != 20 {
+		t.Errorf("expected defaults page=1 limit=20, got %d %d", p.Page, p.Limit)
+	}
+	if p.Ratio != 0.5 {
+		t.Errorf("expected ratio 0.5, got %v", p.Ratio)
+	}
+	if strings.Join(p.Tags, ",") != "a,b" || len(p.IDs) != 2 || p.IDs[1] != 2 {
+		t.Errorf("expected slices to be bound, got %v %v", p.Tags, p.IDs)
+	}
+	if !p.Since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected since: %v", p.Since)
+	}
+	if p.Timeout != 3*time.Second {
+		t.Errorf("expected timeout 3s, got %v", p.Timeout)
+	}
+	if p.Debug == nil || !*p.Debug {
+		t.Error("expected debug pointer to be set to true")
+	}
+	if p.Home.City != "Paris" {
+		t.Errorf("expected nested city 'Paris', got '%s'", p.Home.City)
+	}
+	if p.Work != nil {
+		t.Error("expected nil nested pointer without values")
+	}
+	if p.Ignored != "" {
+		t.Error("expected field tagged '-' to be skipped")
+	}
+}
+
+func TestContext_BindQuery_Errors(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?ratio=x&limit=300&work.zip=75001", nil)
+	c := context.NewContext(httptest.NewRecorder(), req)
+
+	var p listParams
+	err := c.BindQuery(&p)
+
+	var he *context.HTTPError
+	if !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+		t.Fatalf("expected a 400 HTTPError, got %v", err)
+	}
+	var bindErr *context.BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("expected a BindError, got %v", err)
+	}
+	if len(bindErr.Fields) != 2 || bindErr.Fields[0].Field != "limit" || bindErr.Fields[1].Field != "ratio" {
+		t.Errorf("unexpected field errors: %+v", bindErr.Fields)
+	}
+	if p.Work == nil || p.Work.Zip != "75001" {
+		t.Error("expected nested pointer to be allocated when it has values")
+	}
+
+	if err := c.BindQuery(p); err == nil {
+		t.Error("expected an error for a non-pointer target")
+	}
+}
+
+type paging struct {
+	Page int `query:"page"`
+}
+
+type embeddedParams struct {
+	*paging
+	Sort string `query:"sort"`
+}
+
+func TestContext_BindQuery_UnexportedEmbeddedPointer(t *testing.T) {
+	c := context.NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?sort=name", nil))
+	var p embeddedParams
+	if err := c.BindQuery(&p); err != nil || p.Sort != "name" {
+		t.Fatalf("expected the other fields to bind, got %v %+v", err, p)
+	}
+
+	c = context.NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/?page=2", nil))
+	p = embeddedParams{}
+	var bindErr *context.BindError
+	if err := c.BindQuery(&p); !errors.As(err, &bindErr) || bindErr.Fields[0].Field != "paging" {
+		t.Errorf("expected a BindError for the nil embedded pointer, got %v", err)
+	}
+
+	p = embeddedParams{paging: &paging{}}
+	if err := c.BindQuery(&p); err != nil || p.Page != 2 {
+		t.Errorf("expected an allocated embedded pointer to be bound, got %v %+v", err, p.paging)
+	}
+}
+
+func TestContext_BindError_Response(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?page=abc", nil)
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, req)
+
+	var p listParams
+	c.Error(c.BindQuery(&p))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+	var body struct {
+		Error  string               `json:"error"`
+		Fields []context.FieldError `json:"fields"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if len(body.Fields) != 1 || body.Fields[0].Field != "page" {
+		t.Errorf("expected a field error for 'page', got %+v", body.Fields)
+	}
+}
+
+type signup struct {
+	Name    string                  `form:"name" json:"name" xml:"name"`
+	Age     int                     `form:"age" json:"age" xml:"age"`
+	Role    string                  `form:"role" json:"role" xml:"role" default:"member"`
+	Terms   bool                    `form:"terms"`
+	Address address                 `form:"address" json:"address"`
+	Avatar  *multipart.FileHeader   `form:"avatar"`
+	Photos  []*multipart.FileHeader `form:"photos"`
+}
+
+func TestContext_Bind(t *testing.T) {
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	mw.WriteField("name", "Ada")
+	mw.WriteField("age", "36")
+	fw, _ := mw.CreateFormFile("avatar", "ada.png")
+	fw.Write([]byte("png"))
+	mw.CreateFormFile("photos", "1.jpg")
+	mw.CreateFormFile("photos", "2.jpg")
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantRole    string
+		wantCity    string
+	}{
+		{"json", "application/json; charset=utf-8", `{"name":"Ada","age":36,"address":{"city":"London"}}`, "member", "London"},
+		{"json overrides default", "application/json", `{"name":"Ada","age":36,"role":"admin"}`, "admin", ""},
+		{"xml", "application/xml", `<signup><name>Ada</name><age>36</age></signup>`, "member", ""},
+		{"form", "application/x-www-form-urlencoded", "name=Ada&age=36&terms=on&address.city=London", "member", "London"},
+		{"multipart", mw.FormDataContentType(), multipartBody.String(), "member", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			c := context.NewContext(httptest.NewRecorder(), req)
+
+			var s signup
+			if err := c.Bind(&s); err != nil {
+				t.Fatalf("Bind failed: %v", err)
+			}
+			if s.Name != "Ada" || s.Age != 36 {
+				t.Errorf("unexpected values: %+v", s)
+			}
+			if s.Role != tt.wantRole {
+				t.Errorf("expected role '%s', got '%s'", tt.wantRole, s.Role)
+			}
+			if s.Address.City != tt.wantCity {
+				t.Errorf("expected city '%s', got '%s'", tt.wantCity, s.Address.City)
+			}
+			if tt.name == "form" && !s.Terms {
+				t.Error("expected checkbox value 'on' to bind as true")
+			}
+			if tt.name == "multipart" {
+				if s.Avatar == nil || s.Avatar.Filename != "ada.png" {
+					t.Errorf("expected avatar file, got %+v", s.Avatar)
+				}
+				if len(s.Photos) != 2 {
+					t.Errorf("expected 2 photos, got %d", len(s.Photos))
+				}
+			}
+		})
+	}
+}
+
+func TestContext_Bind_Failures(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"unsupported", "text/plain", "hello", http.StatusUnsupportedMediaType},
+		{"malformed json", "application/json", `{"name":`, http.StatusBadRequest},
+		{"invalid form value", "application/x-www-form-urlencoded", "age=old", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			c := context.NewContext(httptest.NewRecorder(), req)
+
+			var s signup
+			var he *context.HTTPError
+			if err := c.Bind(&s); !errors.As(err, &he) || he.Code != tt.want {
+				t.Errorf("expected status %d, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("body too large", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"Ada"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Body = http.MaxBytesReader(rr, req.Body, 4)
+		c := context.NewContext(rr, req)
+
+		var s signup
+		var he *context.HTTPError
+		if err := c.Bind(&s); !errors.As(err, &he) || he.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status 413, got %v", err)
+		}
+	})
+}
+
+func TestContext_Bind_NoBody(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?page=3", nil)
+	c := context.NewContext(httptest.NewRecorder(), req)
+
+	var p listParams
+	if err := c.Bind(&p); err != nil {
+		t.Fatalf("Bind failed: %v", err)
+	}
+	if p.Page != 3 {
+		t.Errorf("expected page 3 from the query string, got %d", p.Page)
+	}
+}
+
+func TestContext_BindHeaderCookieParams(t *testing.T) {
+	type request struct {
+		ID      int64    `param:"id"`
+		Slug    string   `param:"slug"`
+		Token   string   `header:"X-Token"`
+		Accept  []string `header:"Accept-Language"`
+		Session string   `cookie:"session"`
+		Theme   string   `cookie:"theme" default:"light"`
+	}
+
+	req := httptest.NewRequest("GET", "/posts/42/hello", nil)
+	req.Header.Set("X-Token", "secret")
+	req.Header.Add("Accept-Language", "fr")
+	req.Header.Add("Accept-Language", "en")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	c := context.NewContext(httptest.NewRecorder(), req)
+	c.Params = append(c.Params, context.Param{Key: "id", Value: "42"}, context.Param{Key: "slug", Value: "hello"})
+
+	var r request
+	if err := c.BindParams(&r); err != nil {
+		t.Fatalf("BindParams failed: %v", err)
+	}
+	if err := c.BindHeader(&r); err != nil {
+		t.Fatalf("BindHeader failed: %v", err)
+	}
+	if err := c.BindCookie(&r); err != nil {
+		t.Fatalf("BindCookie failed: %v", err)
+	}
+
+	if r.ID != 42 || r.Slug != "hello" {
+		t.Errorf("unexpected params: %d %s", r.ID, r.Slug)
+	}
+	if r.Token != "secret" || strings.Join(r.Accept, ",") != "fr,en" {
+		t.Errorf("unexpected headers: %s %v", r.Token, r.Accept)
+	}
+	if r.Session != "abc" || r.Theme != "light" {
+		t.Errorf("unexpected cookies: %s %s", r.Session, r.Theme)
+	}
+
+	c.Params[0].Value = "x"
+	var he *context.HTTPError
+	if err := c.BindParams(&r); !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid param, got %v", err)
+	}
+}
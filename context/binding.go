@@ -0,0 +1,302 @@
+package context
+
+import (
+	"encoding"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a request field that was rejected.
+type FieldError struct {
+	// Field is the key of the value in the request, such as "page" or
+	// "address.city" for nested structs.
+	Field string `json:"field"`
+	// Rule is the validation rule that failed, empty for binding errors.
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is implemented by errors listing the rejected fields of a
+// request. DefaultErrorHandler renders the list in a "fields" property.
+type FieldErrors interface {
+	error
+	FieldErrors() []FieldError
+}
+
+// BindError is returned when request values cannot be converted to the
+// fields of the target struct.
+type BindError struct {
+	Fields []FieldError
+}
+
+func (e *BindError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + ": " + f.Message
+	}
+	return "binding failed: " + strings.Join(messages, "; ")
+}
+
+func (e *BindError) FieldErrors() []FieldError {
+	return e.Fields
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// timeLayouts are tried in order when binding a time.Time.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// binder fills struct fields tagged with tag from string values.
+type binder struct {
+	tag    string
+	values func(key string) []string
+	files  func(key string) []*multipart.FileHeader
+	errs   []FieldError
+}
+
+// bind fills the struct pointed to by out. It returns a *BindError listing
+// every field whose value could not be converted.
+func (b *binder) bind(out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding requires a pointer to a struct, got %T", out)
+	}
+	b.bindStruct(v.Elem(), "")
+	if len(b.errs) > 0 {
+		return &BindError{Fields: b.errs}
+	}
+	return nil
+}
+
+// bindStruct binds the fields of v and reports whether any value was found.
+func (b *binder) bindStruct(v reflect.Value, prefix string) bool {
+	found := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		name := tagName(field, b.tag)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			if fv.Kind() == reflect.Ptr && fv.IsNil() && !fv.CanSet() {
+				// Like encoding/json, a nil pointer to an unexported
+				// embedded struct cannot be allocated, so its fields are
+				// rejected when the request has values for them.
+				if b.bindStruct(reflect.New(field.Type.Elem()).Elem(), prefix) {
+					b.errs = append(b.errs, FieldError{Field: prefix + field.Name, Message: "cannot set embedded pointer to unexported struct"})
+				}
+				continue
+			}
+			found = b.bindNested(fv, prefix) || found
+			continue
+		}
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		found = b.bindField(fv, field, prefix+name) || found
+	}
+	return found
+}
+
+func (b *binder) bindField(fv reflect.Value, field reflect.StructField, key string) bool {
+	if b.files != nil && (field.Type == fileHeaderType || field.Type == reflect.SliceOf(fileHeaderType)) {
+		files := b.files(key)
+		if len(files) == 0 {
+			return false
+		}
+		if field.Type == fileHeaderType {
+			fv.Set(reflect.ValueOf(files[0]))
+		} else {
+			fv.Set(reflect.ValueOf(files))
+		}
+		return true
+	}
+
+	if isNestedStruct(field.Type) {
+		return b.bindNested(fv, key+".")
+	}
+
+	values := b.values(key)
+	if len(values) == 0 {
+		def, ok := field.Tag.Lookup("default")
+		if !ok {
+			return false
+		}
+		values = []string{def}
+	}
+	if err := setValues(fv, values); err != nil {
+		b.errs = append(b.errs, FieldError{Field: key, Message: err.Error()})
+	}
+	return true
+}
+
+// bindNested binds a struct field, allocating a nil pointer only when one
+// of its fields has a value.
+func (b *binder) bindNested(fv reflect.Value, prefix string) bool {
+	if fv.Kind() != reflect.Ptr {
+		return b.bindStruct(fv, prefix)
+	}
+	if !fv.IsNil() {
+		return b.bindStruct(fv.Elem(), prefix)
+	}
+	target := reflect.New(fv.Type().Elem())
+	if !b.bindStruct(target.Elem(), prefix) {
+		return false
+	}
+	fv.Set(target)
+	return true
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	return name
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isNestedStruct reports whether t is a struct bound field by field, as
+// opposed to one decoded from a single value like time.Time.
+func isNestedStruct(t reflect.Type) bool {
+	t = indirectType(t)
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValues stores values in v: every value for a slice, the first one
+// otherwise.
+func setValues(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr {
+		target := reflect.New(v.Type().Elem())
+		if err := setValues(target.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(target)
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValues(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+func setValue(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == timeType:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				v.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid time %q", value)
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType):
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("invalid value %q: %v", value, err)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		if value == "on" {
+			value = "true"
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		v.SetBytes([]byte(value))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// applyDefaults sets the zero fields of the struct pointed to by out that
+// have a `default` tag, before a body is decoded over them.
+func applyDefaults(out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	var errs []FieldError
+	applyStructDefaults(v.Elem(), "", &errs)
+	if len(errs) > 0 {
+		return &BindError{Fields: errs}
+	}
+	return nil
+}
+
+func applyStructDefaults(v reflect.Value, prefix string, errs *[]FieldError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if isNestedStruct(field.Type) && field.Type.Kind() == reflect.Struct {
+			applyStructDefaults(fv, prefix+field.Name+".", errs)
+			continue
+		}
+		def, ok := field.Tag.Lookup("default")
+		if !ok || !fv.IsZero() {
+			continue
+		}
+		if err := setValues(fv, []string{def}); err != nil {
+			*errs = append(*errs, FieldError{Field: prefix + field.Name, Message: err.Error()})
+		}
+	}
+}
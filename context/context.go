@@ -3,30 +3,196 @@ package context
 // core context
 
 import (
+	"math"
 	"net/http"
+	"reflect"
+	"runtime"
 )
 
 type Context struct {
 	Writer  http.ResponseWriter
 	Request *http.Request
-	Params  map[string]string
+	Params  Params
 	Keys    map[string]interface{}
+
+	errorHandler ErrorHandler
+	urlBuilder   URLBuilder
+	proxy        *ProxyConfig
+
+	handlers HandlersChain
+	index    int
 }
 
 type HandlerFunc func(*Context)
 
 type Middleware func(HandlerFunc) HandlerFunc
 
+// HandlersChain is the ordered list of handlers run for a request: the
+// middleware first, the route handler last. Each one continues the chain by
+// calling Context.Next.
+type HandlersChain []HandlerFunc
+
+// abortIndex is larger than any chain, so Next stops once it is set.
+const abortIndex = math.MaxInt / 2
+
+// FromMiddleware adapts a Middleware to a chain handler. Calling next inside
+// the middleware runs the rest of the chain; returning without calling it
+// aborts the chain. So does returning before the rest of the chain
+// finished, as when the middleware recovers a panic raised further down.
+func FromMiddleware(middleware Middleware) HandlerFunc {
+	handler := middleware(func(c *Context) {
+		c.Next()
+	})
+	return func(c *Context) {
+		handler(c)
+		if c.index < len(c.handlers)-1 {
+			c.Abort()
+		}
+	}
+}
+
+// URLBuilder builds the path of a named route. It is implemented by
+// router.Router.
+type URLBuilder interface {
+	Reverse(name string, params ...interface{}) string
+}
+
+// Param is a single route parameter.
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params holds route parameters in the order they appear in the path. It is
+// a slice so the router can reuse its storage between requests.
+type Params []Param
+
+// Get returns the value of the named parameter and whether it exists.
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName returns the value of the named parameter, or "" if it is missing.
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
 func NewContext(writer http.ResponseWriter, request *http.Request) *Context {
 	return &Context{
 		Writer:  writer,
 		Request: request,
-		Params:  make(map[string]string),
+		Params:  make(Params, 0),
 		Keys:    make(map[string]interface{}),
+		index:   -1,
+	}
+}
+
+// Reset prepares a pooled Context for a new request. The parameter storage
+// is kept and Keys is allocated again on the first Set.
+func (c *Context) Reset(writer http.ResponseWriter, request *http.Request) {
+	c.Writer = writer
+	c.Request = request
+	c.Params = c.Params[:0]
+	c.handlers = nil
+	c.index = -1
+	c.Keys = nil
+	c.errorHandler = nil
+	c.urlBuilder = nil
+	c.proxy = nil
+}
+
+// SetHandlers sets the chain run by Next and rewinds it to the start.
+func (c *Context) SetHandlers(handlers HandlersChain) {
+	c.handlers = handlers
+	c.index = -1
+}
+
+// Next runs the remaining handlers of the chain. Middleware calls it to
+// run code after the handlers that follow it.
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
 	}
 }
 
+// Abort prevents the remaining handlers of the chain from running. The
+// current handler still runs to completion.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted reports whether the chain was aborted.
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus aborts the chain and writes the status code.
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.Writer.WriteHeader(code)
+}
+
+// AbortWithStatusJSON aborts the chain and writes obj as JSON.
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) error {
+	c.Abort()
+	return c.JSON(code, obj)
+}
+
+// AbortWithError aborts the chain and passes err to the ErrorHandler.
+func (c *Context) AbortWithError(err error) {
+	c.Abort()
+	c.Error(err)
+}
+
+// HandlerName returns the function name of the last handler of the chain,
+// usually the route handler.
+func (c *Context) HandlerName() string {
+	if len(c.handlers) == 0 {
+		return ""
+	}
+	return nameOfFunction(c.handlers[len(c.handlers)-1])
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// SetErrorHandler sets the handler used by Error to render failures.
+func (c *Context) SetErrorHandler(handler ErrorHandler) {
+	c.errorHandler = handler
+}
+
+// SetURLBuilder sets the resolver used by URLFor.
+func (c *Context) SetURLBuilder(builder URLBuilder) {
+	c.urlBuilder = builder
+}
+
+// URLFor returns the path of the named route, see router.Router.Reverse for
+// how params are used. It returns "" if the route cannot be built.
+func (c *Context) URLFor(name string, params ...interface{}) string {
+	if c.urlBuilder == nil {
+		return ""
+	}
+	return c.urlBuilder.Reverse(name, params...)
+}
+
+// Param returns the value of the named route parameter.
+func (c *Context) Param(name string) string {
+	return c.Params.ByName(name)
+}
+
 func (c *Context) Set(key string, value interface{}) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
 	c.Keys[key] = value
 }
 
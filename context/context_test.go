@@ -1,6 +1,7 @@
 package context_test
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -131,8 +132,10 @@ func TestContext_RemoteIP(t *testing.T) {
 
 	t.Run("X-Real-IP", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "127.0.0.1:8080"
 		req.Header.Set("X-Real-IP", "192.168.1.1")
 		ctx := context.NewContext(nil, req)
+		ctx.SetProxyConfig(loopbackProxy("X-Real-IP"))
 		if ctx.RemoteIP() != "192.168.1.1" {
 			t.Errorf("Expected IP 192.168.1.1, got %s", ctx.RemoteIP())
 		}
@@ -140,10 +143,99 @@ func TestContext_RemoteIP(t *testing.T) {
 
 	t.Run("X-Forwarded-For", func(t *testing.T) {
 		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "127.0.0.1:8080"
 		req.Header.Set("X-Forwarded-For", "203.0.113.195, 70.41.3.18, 150.172.238.178")
 		ctx := context.NewContext(nil, req)
-		if ctx.RemoteIP() != "203.0.113.195" {
-			t.Errorf("Expected IP 203.0.113.195, got %s", ctx.RemoteIP())
+		if ctx.RemoteIP() != "127.0.0.1" {
+			t.Errorf("Expected untrusted header to be ignored, got %s", ctx.RemoteIP())
 		}
+		ctx.SetProxyConfig(loopbackProxy(""))
+		if ctx.RemoteIP() != "150.172.238.178" {
+			t.Errorf("Expected IP 150.172.238.178, got %s", ctx.RemoteIP())
+		}
+	})
+}
+
+func loopbackProxy(header string) *context.ProxyConfig {
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	return &context.ProxyConfig{TrustedProxies: []*net.IPNet{loopback}, Header: header}
+}
+
+func TestContext_NextAndAbort(t *testing.T) {
+	var order []string
+	step := func(name string) context.HandlerFunc {
+		return func(c *context.Context) {
+			order = append(order, name+":before")
+			c.Next()
+			order = append(order, name+":after")
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx := context.NewContext(httptest.NewRecorder(), req)
+	ctx.SetHandlers(context.HandlersChain{step("a"), step("b"), func(c *context.Context) {
+		order = append(order, "handler")
+	}})
+	ctx.Next()
+
+	want := "a:before,b:before,handler,b:after,a:after"
+	if got := strings.Join(order, ","); got != want {
+		t.Errorf("expected order %s, got %s", want, got)
+	}
+
+	rr := httptest.NewRecorder()
+	ctx = context.NewContext(rr, req)
+	handlerRan := false
+	ctx.SetHandlers(context.HandlersChain{
+		func(c *context.Context) {
+			_ = c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+		},
+		func(c *context.Context) { handlerRan = true },
 	})
+	ctx.Next()
+
+	if handlerRan {
+		t.Error("expected handler not to run after Abort")
+	}
+	if !ctx.IsAborted() {
+		t.Error("expected IsAborted to be true")
+	}
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func namedHandler(c *context.Context) {}
+
+func TestContext_FromMiddleware(t *testing.T) {
+	blocking := context.FromMiddleware(func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			if c.GetHeader("Authorization") == "" {
+				_ = c.Text(http.StatusUnauthorized, "no")
+				return
+			}
+			next(c)
+		}
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	ctx := context.NewContext(httptest.NewRecorder(), req)
+	ran := false
+	ctx.SetHandlers(context.HandlersChain{blocking, func(c *context.Context) { ran = true }, namedHandler})
+	ctx.Next()
+	if ran || !ctx.IsAborted() {
+		t.Error("expected a middleware that does not call next to abort the chain")
+	}
+
+	if name := ctx.HandlerName(); !strings.HasSuffix(name, ".namedHandler") {
+		t.Errorf("expected HandlerName to end with '.namedHandler', got '%s'", name)
+	}
+
+	req.Header.Set("Authorization", "token")
+	ctx = context.NewContext(httptest.NewRecorder(), req)
+	ctx.SetHandlers(context.HandlersChain{blocking, func(c *context.Context) { ran = true }})
+	ctx.Next()
+	if !ran {
+		t.Error("expected next to continue the chain")
+	}
 }
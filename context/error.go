@@ -0,0 +1,105 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// HTTPError is an error carrying the HTTP status code and the public message
+// sent to the client. Err holds the internal cause, which is logged but never
+// exposed in the response.
+type HTTPError struct {
+	Code    int
+	Message string
+	Err     error
+}
+
+// NewHTTPError creates an HTTPError for the given status code. The message
+// defaults to the standard status text.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	he := &HTTPError{Code: code, Message: http.StatusText(code)}
+	if len(message) > 0 {
+		he.Message = message[0]
+	}
+	return he
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("code=%d, message=%s, err=%v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("code=%d, message=%s", e.Code, e.Message)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap sets the internal cause of the error and returns it for chaining.
+func (e *HTTPError) Wrap(err error) *HTTPError {
+	e.Err = err
+	return e
+}
+
+// ErrorHandler turns an error returned by a handler into a response.
+type ErrorHandler func(*Context, error)
+
+// HandlerFuncE is a handler that reports failures by returning an error.
+type HandlerFuncE func(*Context) error
+
+// MiddlewareE is the error-returning counterpart of Middleware.
+type MiddlewareE func(HandlerFuncE) HandlerFuncE
+
+// ToHandler adapts an error-returning handler so it can be registered on a
+// router. A returned error is passed to the context's ErrorHandler.
+func ToHandler(handler HandlerFuncE) HandlerFunc {
+	return func(c *Context) {
+		if err := handler(c); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// ToMiddleware adapts an error-returning middleware to a Middleware.
+func ToMiddleware(middleware MiddlewareE) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return ToHandler(middleware(func(c *Context) error {
+			next(c)
+			return nil
+		}))
+	}
+}
+
+// DefaultErrorHandler writes the error as a JSON body of the form
+// {"error": message}, adding the "fields" of FieldErrors. A body over the
+// size limit becomes a 413; other errors that are not an *HTTPError become a
+// 500 and their details stay in the logs.
+func DefaultErrorHandler(c *Context, err error) {
+	code := http.StatusInternalServerError
+	message := http.StatusText(code)
+
+	var he *HTTPError
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &he) {
+		code = he.Code
+		message = he.Message
+	} else if errors.As(err, &tooLarge) {
+		code = http.StatusRequestEntityTooLarge
+		message = http.StatusText(code)
+	}
+
+	if code >= http.StatusInternalServerError {
+		log.Println("Error:", err)
+	}
+
+	body := map[string]interface{}{"error": message}
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		body["fields"] = fields.FieldErrors()
+	}
+	if jsonErr := c.JSON(code, body); jsonErr != nil {
+		log.Printf("could not send error response: %v", jsonErr)
+	}
+}
@@ -0,0 +1,97 @@
+package context
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTPError(t *testing.T) {
+	he := NewHTTPError(http.StatusNotFound)
+	if he.Message != "Not Found" {
+		t.Errorf("expected default message 'Not Found', got '%s'", he.Message)
+	}
+
+	cause := errors.New("db down")
+	he = NewHTTPError(http.StatusServiceUnavailable, "try later").Wrap(cause)
+	if he.Message != "try later" {
+		t.Errorf("expected message 'try later', got '%s'", he.Message)
+	}
+	if !errors.Is(he, cause) {
+		t.Error("expected HTTPError to unwrap to its cause")
+	}
+}
+
+func TestErrorDefaultHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+
+	ctx, rr := newTestContext(req)
+	ctx.Error(errors.New("boom"))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+	if body := rr.Body.String(); body != "{\"error\":\"Internal Server Error\"}\n" {
+		t.Errorf("unexpected body: %s", body)
+	}
+
+	ctx, rr = newTestContext(req)
+	ctx.Error(NewHTTPError(http.StatusBadRequest, "bad id").Wrap(errors.New("strconv failed")))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if body := rr.Body.String(); body != "{\"error\":\"bad id\"}\n" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestErrorCustomHandler(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	ctx, rr := newTestContext(req)
+
+	var got error
+	ctx.SetErrorHandler(func(c *Context, err error) {
+		got = err
+		_ = c.Text(http.StatusTeapot, "custom")
+	})
+
+	handler := ToHandler(func(c *Context) error {
+		return NewHTTPError(http.StatusForbidden)
+	})
+	handler(ctx)
+
+	if got == nil {
+		t.Fatal("expected custom error handler to be called")
+	}
+	if rr.Code != http.StatusTeapot || rr.Body.String() != "custom" {
+		t.Errorf("expected custom response, got %d '%s'", rr.Code, rr.Body.String())
+	}
+}
+
+func TestToMiddleware(t *testing.T) {
+	auth := ToMiddleware(func(next HandlerFuncE) HandlerFuncE {
+		return func(c *Context) error {
+			if c.GetHeader("Authorization") == "" {
+				return NewHTTPError(http.StatusUnauthorized)
+			}
+			return next(c)
+		}
+	})
+	handler := auth(func(c *Context) {
+		_ = c.Text(http.StatusOK, "OK")
+	})
+
+	ctx, rr := newTestContext(httptest.NewRequest("GET", "/", nil))
+	handler(ctx)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "token")
+	ctx, rr = newTestContext(req)
+	handler(ctx)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
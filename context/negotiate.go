@@ -0,0 +1,187 @@
+package context
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Accepts returns the offer the client prefers according to the Accept
+// header, or "" when none is acceptable. Offers are media types such as
+// "application/json" or extensions such as "json". Without an Accept
+// header the first offer is returned; among offers of equal quality, the
+// first one wins.
+func (c *Context) Accepts(offers ...string) string {
+	mediaTypes := make([]string, len(offers))
+	for i, offer := range offers {
+		mediaTypes[i] = offerMediaType(offer)
+	}
+	ranked := rankOffers(c.GetHeader("Accept"), mediaTypes, matchMediaType)
+	if len(ranked) == 0 {
+		return ""
+	}
+	return offers[ranked[0]]
+}
+
+// AcceptsEncodings returns the content coding, such as "gzip" or "br", the
+// client prefers according to the Accept-Encoding header, or "". The
+// "identity" coding is acceptable unless the header excludes it.
+func (c *Context) AcceptsEncodings(offers ...string) string {
+	header := c.GetHeader("Accept-Encoding")
+	if strings.TrimSpace(header) != "" {
+		explicit := false
+		for _, r := range parseAccept(header) {
+			if r.value == "identity" || r.value == "*" {
+				explicit = true
+			}
+		}
+		if !explicit {
+			header += ", identity;q=0.001"
+		}
+	}
+	return pickOffer(header, offers, matchExact)
+}
+
+// AcceptsLanguages returns the language tag the client prefers according
+// to the Accept-Language header, or "". A range such as "en" matches the
+// more specific "en-US".
+func (c *Context) AcceptsLanguages(offers ...string) string {
+	return pickOffer(c.GetHeader("Accept-Language"), offers, matchLanguage)
+}
+
+func pickOffer(header string, offers []string, match func(pattern, offer string) int) string {
+	ranked := rankOffers(header, offers, match)
+	if len(ranked) == 0 {
+		return ""
+	}
+	return offers[ranked[0]]
+}
+
+// extensionTypes resolves common extensions independently of the system's
+// MIME tables, which map "xml" to "text/xml" on some platforms.
+var extensionTypes = map[string]string{
+	"json": "application/json",
+	"xml":  "application/xml",
+	"html": "text/html",
+	"text": "text/plain",
+	"txt":  "text/plain",
+	"csv":  "text/csv",
+	"js":   "application/javascript",
+}
+
+// offerMediaType resolves an extension offer such as "json" to its media
+// type, without parameters.
+func offerMediaType(offer string) string {
+	if !strings.Contains(offer, "/") {
+		ext := strings.ToLower(strings.TrimPrefix(offer, "."))
+		if t, ok := extensionTypes[ext]; ok {
+			offer = t
+		} else if t := mime.TypeByExtension("." + ext); t != "" {
+			offer = t
+		}
+	}
+	offer, _, _ = strings.Cut(offer, ";")
+	return strings.TrimSpace(offer)
+}
+
+// acceptRange is one entry of an Accept header, such as "text/*;q=0.8".
+type acceptRange struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses the ranges of an Accept-style header. Ranges with q=0
+// are kept so they can exclude an offer.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil && parsed >= 0 && parsed <= 1 {
+					q = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{value: value, q: q})
+	}
+	return ranges
+}
+
+// matchMediaType reports how precisely a media range matches an offer: 3
+// for an exact match, 2 for "type/*", 1 for "*/*" and 0 for no match.
+func matchMediaType(pattern, offer string) int {
+	switch {
+	case pattern == offer:
+		return 3
+	case pattern == "*/*" || pattern == "*":
+		return 1
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(offer, pattern[:len(pattern)-1]):
+		return 2
+	}
+	return 0
+}
+
+// matchExact matches tokens such as content codings, and "*".
+func matchExact(pattern, offer string) int {
+	switch pattern {
+	case offer:
+		return 2
+	case "*":
+		return 1
+	}
+	return 0
+}
+
+// matchLanguage matches language ranges by prefix, so "en" matches "en-us".
+func matchLanguage(pattern, offer string) int {
+	switch {
+	case pattern == offer:
+		return 3
+	case strings.HasPrefix(offer, pattern+"-"):
+		return 2
+	case pattern == "*":
+		return 1
+	}
+	return 0
+}
+
+// rankOffers returns the indexes of the acceptable offers, from the most to
+// the least preferred by the header. An empty header accepts every offer in
+// order. The most specific range matching an offer sets its quality; ties
+// keep the order of the offers.
+func rankOffers(header string, offers []string, match func(pattern, offer string) int) []int {
+	var ranked []int
+	if strings.TrimSpace(header) == "" {
+		for i := range offers {
+			ranked = append(ranked, i)
+		}
+		return ranked
+	}
+
+	ranges := parseAccept(header)
+	quality := make([]float64, len(offers))
+	for i, offer := range offers {
+		normalized := strings.ToLower(offer)
+		precision := 0
+		for _, r := range ranges {
+			if p := match(r.value, normalized); p > precision {
+				quality[i], precision = r.q, p
+			}
+		}
+		if quality[i] > 0 {
+			ranked = append(ranked, i)
+		}
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return quality[ranked[a]] > quality[ranked[b]]
+	})
+	return ranked
+}
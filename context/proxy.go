@@ -0,0 +1,177 @@
+package context
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/arthurlch/goryu/internal/utils"
+)
+
+// ProxyConfig tells a Context which peers are trusted proxies. Forwarding
+// headers are only read from requests sent by one of them.
+type ProxyConfig struct {
+	// TrustedProxies are the networks of the trusted proxies.
+	TrustedProxies []*net.IPNet
+	// Header carries the client IP: "X-Forwarded-For", "Forwarded" or a
+	// header holding a single address, such as "X-Real-IP".
+	// Default: "X-Forwarded-For"
+	Header string
+}
+
+// SetProxyConfig sets the trusted proxies used by RemoteIP, Protocol,
+// Hostname and BaseURL. Without one, forwarding headers are ignored.
+func (c *Context) SetProxyConfig(config *ProxyConfig) {
+	c.proxy = config
+}
+
+// peerIP returns the IP address of the peer that sent the request.
+func (c *Context) peerIP() string {
+	ip, _, err := net.SplitHostPort(c.Request.RemoteAddr)
+	if err != nil {
+		return c.Request.RemoteAddr
+	}
+	return ip
+}
+
+func (c *Context) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && utils.ContainsIP(c.proxy.TrustedProxies, parsed)
+}
+
+// fromTrustedProxy reports whether the forwarding headers can be read.
+func (c *Context) fromTrustedProxy() bool {
+	return c.proxy != nil && c.isTrusted(c.peerIP())
+}
+
+// forwardedClientIP returns the client address from the proxy header, or ""
+// when it holds none or an invalid one.
+func (c *Context) forwardedClientIP() string {
+	header := http.CanonicalHeaderKey(c.proxy.Header)
+	var hops []string
+	switch header {
+	case "", "X-Forwarded-For":
+		hops = headerList(c.Request.Header.Values("X-Forwarded-For"))
+	case "Forwarded":
+		for _, element := range forwardedElements(c.Request.Header.Values("Forwarded")) {
+			hops = append(hops, forwardedNode(element["for"]))
+		}
+	default:
+		ip := strings.TrimSpace(c.Request.Header.Get(header))
+		if net.ParseIP(ip) == nil {
+			return ""
+		}
+		return ip
+	}
+
+	// Walk the hops from the closest one and stop at the first address
+	// that is not a trusted proxy: anything left of it could be forged.
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			return ""
+		}
+		if i == 0 || !c.isTrusted(hops[i]) {
+			return hops[i]
+		}
+	}
+	return ""
+}
+
+// forwardedValue returns the value recorded for key ("proto" or "host") by
+// the trusted proxies in the Forwarded header, or else the last value of
+// xHeader. Like forwardedClientIP, it reads from the closest hop: values on
+// the left were sent by the client and could be forged.
+func (c *Context) forwardedValue(key, xHeader string) string {
+	elements := forwardedElements(c.Request.Header.Values("Forwarded"))
+	value := ""
+	// Each element is added by a proxy; it was sent by another trusted
+	// proxy only when the "for" node of the element is trusted.
+	for i := len(elements) - 1; i >= 0; i-- {
+		if v := elements[i][key]; v != "" {
+			value = v
+		}
+		if !c.isTrusted(forwardedNode(elements[i]["for"])) {
+			break
+		}
+	}
+	if value != "" {
+		return value
+	}
+	if values := headerList(c.Request.Header.Values(xHeader)); len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return ""
+}
+
+// headerList splits comma-separated header values into trimmed items.
+func headerList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// forwardedElements parses RFC 7239 Forwarded header values into one map of
+// lower-cased parameters per element, e.g. for=192.0.2.60;proto=https.
+func forwardedElements(values []string) []map[string]string {
+	var elements []map[string]string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			pairs := make(map[string]string)
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				val = strings.TrimSpace(val)
+				if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+					val = strings.ReplaceAll(val[1:len(val)-1], `\"`, `"`)
+				}
+				pairs[strings.ToLower(strings.TrimSpace(key))] = val
+			}
+			if len(pairs) > 0 {
+				elements = append(elements, pairs)
+			}
+		}
+	}
+	return elements
+}
+
+// splitQuoted splits s at sep outside of quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// forwardedNode returns the IP of a Forwarded node such as "192.0.2.43",
+// "192.0.2.43:80" or "[2001:db8::1]:4711". Obfuscated and unknown nodes are
+// returned as is and fail IP parsing.
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if end := strings.IndexByte(node, ']'); end > 0 {
+			return node[1:end]
+		}
+		return node
+	}
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return node
+}
@@ -0,0 +1,207 @@
+package context
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedValue is returned by a Renderer that cannot encode a value,
+// so Render tries the next acceptable format.
+var ErrUnsupportedValue = errors.New("renderer does not support the value")
+
+// Renderer encodes response values in one media type.
+type Renderer interface {
+	Render(w io.Writer, v interface{}) error
+}
+
+// RendererFunc adapts a function to a Renderer.
+type RendererFunc func(w io.Writer, v interface{}) error
+
+func (f RendererFunc) Render(w io.Writer, v interface{}) error {
+	return f(w, v)
+}
+
+type registeredRenderer struct {
+	mediaType string
+	renderer  Renderer
+}
+
+// renderers are kept in registration order, which breaks ties when the
+// client accepts several media types equally.
+var renderers = struct {
+	sync.RWMutex
+	list []registeredRenderer
+}{list: []registeredRenderer{
+	{"application/json", RendererFunc(func(w io.Writer, v interface{}) error {
+		return json.NewEncoder(w).Encode(v)
+	})},
+	{"application/xml", RendererFunc(renderXML)},
+	{"text/csv", RendererFunc(renderCSV)},
+}}
+
+// RegisterRenderer makes Render able to respond with mediaType, or replaces
+// the renderer registered for it. It panics if mediaType is empty or r is
+// nil.
+func RegisterRenderer(mediaType string, r Renderer) {
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if mediaType == "" || r == nil {
+		panic(fmt.Sprintf("context: invalid renderer for media type %q", mediaType))
+	}
+	renderers.Lock()
+	defer renderers.Unlock()
+	// The list is copied so Render can use its snapshot without locking.
+	list := append([]registeredRenderer(nil), renderers.list...)
+	for i, registered := range list {
+		if registered.mediaType == mediaType {
+			list[i].renderer = r
+			renderers.list = list
+			return
+		}
+	}
+	renderers.list = append(list, registeredRenderer{mediaType, r})
+}
+
+// Render encodes v with the registered renderer the client prefers,
+// according to the Accept header, and sends it with code. It returns a 406
+// *HTTPError when no renderer is acceptable. Nothing is written when
+// encoding fails, so the error can still be passed to Context.Error.
+func (c *Context) Render(code int, v interface{}) error {
+	renderers.RLock()
+	list := renderers.list
+	renderers.RUnlock()
+
+	mediaTypes := make([]string, len(list))
+	for i, registered := range list {
+		mediaTypes[i] = registered.mediaType
+	}
+
+	c.Writer.Header().Add("Vary", "Accept")
+	var buf bytes.Buffer
+	for _, i := range rankOffers(c.GetHeader("Accept"), mediaTypes, matchMediaType) {
+		buf.Reset()
+		err := list[i].renderer.Render(&buf, v)
+		if errors.Is(err, ErrUnsupportedValue) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		return c.Data(code, list[i].mediaType, buf.Bytes())
+	}
+	return NewHTTPError(http.StatusNotAcceptable)
+}
+
+// Negotiate runs the function of the media type the client prefers, after
+// setting the Content-Type and writing code. The function writes the body.
+// Media types of equal preference are chosen in alphabetical order. It
+// returns a 406 *HTTPError, without writing, when none is acceptable.
+//
+//	c.Negotiate(http.StatusOK, map[string]func(){
+//		"application/json": func() { json.NewEncoder(c.Writer).Encode(user) },
+//		"text/html":        func() { userTemplate.Execute(c.Writer, user) },
+//	})
+func (c *Context) Negotiate(code int, offers map[string]func()) error {
+	mediaTypes := make([]string, 0, len(offers))
+	for mediaType := range offers {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	c.Writer.Header().Add("Vary", "Accept")
+	mediaType := c.Accepts(mediaTypes...)
+	if mediaType == "" {
+		return NewHTTPError(http.StatusNotAcceptable)
+	}
+	c.Writer.Header().Set("Content-Type", offerMediaType(mediaType))
+	c.Writer.WriteHeader(code)
+	offers[mediaType]()
+	return nil
+}
+
+// renderXML encodes v as XML. Maps cannot be encoded and top-level slices
+// would have no root element, so both are left to the next format.
+func renderXML(w io.Writer, v interface{}) error {
+	if t := reflect.TypeOf(v); t != nil && indirectType(t).Kind() == reflect.Slice {
+		return ErrUnsupportedValue
+	}
+	err := xml.NewEncoder(w).Encode(v)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return ErrUnsupportedValue
+	}
+	return err
+}
+
+// renderCSV writes a [][]string, or a slice of structs with a header row
+// named after their `csv` or `json` tags.
+func renderCSV(w io.Writer, v interface{}) error {
+	cw := csv.NewWriter(w)
+	if records, ok := v.([][]string); ok {
+		return cw.WriteAll(records)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return ErrUnsupportedValue
+	}
+	elem := rv.Type().Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return ErrUnsupportedValue
+	}
+
+	var header []string
+	var fields []int
+	for i := 0; i < elem.NumField(); i++ {
+		field := elem.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := tagName(field, "csv")
+		if name == "" {
+			name = tagName(field, "json")
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(fields))
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		for j, index := range fields {
+			record[j] = fmt.Sprint(item.Field(index).Interface())
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
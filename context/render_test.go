@@ -0,0 +1,226 @@
+package context_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arthurlch/goryu/context"
+)
+
+func TestContext_Accepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"", []string{"json", "xml"}, "json"},
+		{"application/xml", []string{"json", "xml"}, "xml"},
+		{"text/html, application/json;q=0.9, */*;q=0.1", []string{"application/xml", "application/json"}, "application/json"},
+		{"text/*;q=0.5, text/csv", []string{"text/plain", "text/csv"}, "text/csv"},
+		{"application/json;q=0, */*", []string{"application/json", "text/plain"}, "text/plain"},
+		{"application/json;q=0", []string{"json"}, ""},
+		{"image/png", []string{"json", "html"}, ""},
+		{"TEXT/HTML;level=1", []string{"html"}, "html"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", tt.accept)
+		c := context.NewContext(httptest.NewRecorder(), req)
+		if got := c.Accepts(tt.offers...); got != tt.want {
+			t.Errorf("Accepts(%q, %v) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+		}
+	}
+}
+
+func TestContext_AcceptsEncodingsAndLanguages(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.8, br")
+	req.Header.Set("Accept-Language", "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.1")
+	c := context.NewContext(httptest.NewRecorder(), req)
+
+	if got := c.AcceptsEncodings("gzip", "br"); got != "br" {
+		t.Errorf("expected 'br', got %q", got)
+	}
+	if got := c.AcceptsEncodings("deflate", "identity"); got != "identity" {
+		t.Errorf("expected 'identity' to stay acceptable, got %q", got)
+	}
+	if got := c.AcceptsLanguages("en-US", "fr"); got != "fr" {
+		t.Errorf("expected 'fr', got %q", got)
+	}
+	if got := c.AcceptsLanguages("en-GB", "de"); got != "en-GB" {
+		t.Errorf("expected 'en' to match 'en-GB', got %q", got)
+	}
+
+	req.Header.Set("Accept-Encoding", "gzip, *;q=0")
+	if got := c.AcceptsEncodings("identity"); got != "" {
+		t.Errorf("expected identity to be excluded, got %q", got)
+	}
+}
+
+type product struct {
+	ID    int     `json:"id" xml:"id"`
+	Name  string  `json:"name" xml:"name"`
+	Price float64 `csv:"price_eur" json:"price" xml:"price"`
+	Notes string  `json:"-" xml:"-" csv:"-"`
+}
+
+func TestContext_Render(t *testing.T) {
+	products := []product{{1, "Tea", 4.5, "x"}, {2, "Cake, large", 12, ""}}
+
+	tests := []struct {
+		name       string
+		accept     string
+		value      interface{}
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"default json", "", products[0], http.StatusOK, "application/json", `{"id":1,"name":"Tea","price":4.5}`},
+		{"xml", "application/xml", products[0], http.StatusOK, "application/xml", `<product><id>1</id><name>Tea</name><price>4.5</price></product>`},
+		{"csv", "text/csv", products, http.StatusOK, "text/csv", "id,name,price_eur\n1,Tea,4.5\n2,\"Cake, large\",12\n"},
+		{"csv unsupported falls back", "text/csv, application/json;q=0.1", products[0], http.StatusOK, "application/json", `{"id":1,"name":"Tea","price":4.5}`},
+		{"browser with a map", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", map[string]string{"a": "b"}, http.StatusOK, "application/json", `{"a":"b"}`},
+		{"browser with a slice", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", products[:1], http.StatusOK, "application/json", `[{"id":1,"name":"Tea","price":4.5}]`},
+		{"not acceptable", "image/png", products[0], http.StatusNotAcceptable, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", tt.accept)
+			rr := httptest.NewRecorder()
+			c := context.NewContext(rr, req)
+
+			err := c.Render(http.StatusOK, tt.value)
+			if tt.wantStatus == http.StatusNotAcceptable {
+				var he *context.HTTPError
+				if !errors.As(err, &he) || he.Code != http.StatusNotAcceptable {
+					t.Fatalf("expected a 406 HTTPError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if rr.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("expected Content-Type %s, got %s", tt.wantType, rr.Header().Get("Content-Type"))
+			}
+			if rr.Header().Get("Vary") != "Accept" {
+				t.Error("expected Vary: Accept")
+			}
+			if strings.TrimSuffix(rr.Body.String(), "\n") != strings.TrimSuffix(tt.wantBody, "\n") {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	context.RegisterRenderer("text/plain", context.RendererFunc(func(w io.Writer, v interface{}) error {
+		_, err := fmt.Fprintf(w, "%v", v)
+		return err
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/plain")
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, req)
+
+	if err := c.Render(http.StatusCreated, "hello"); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if rr.Code != http.StatusCreated || rr.Body.String() != "hello" || rr.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected response: %d %s %q", rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	offers := func(c *context.Context) map[string]func() {
+		return map[string]func(){
+			"application/json": func() { c.Writer.Write([]byte(`{"ok":true}`)) },
+			"text/html":        func() { c.Writer.Write([]byte("<p>ok</p>")) },
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, req)
+	if err := c.Negotiate(http.StatusAccepted, offers(c)); err != nil {
+		t.Fatalf("Negotiate failed: %v", err)
+	}
+	if rr.Code != http.StatusAccepted || rr.Header().Get("Content-Type") != "text/html" || rr.Body.String() != "<p>ok</p>" {
+		t.Errorf("unexpected response: %d %s %q", rr.Code, rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+
+	req.Header.Set("Accept", "application/xml")
+	rr = httptest.NewRecorder()
+	c = context.NewContext(rr, req)
+	var he *context.HTTPError
+	if err := c.Negotiate(http.StatusOK, offers(c)); !errors.As(err, &he) || he.Code != http.StatusNotAcceptable {
+		t.Errorf("expected a 406 HTTPError, got %v", err)
+	}
+	if rr.Body.Len() != 0 {
+		t.Error("expected nothing to be written")
+	}
+}
+
+func TestContext_JSONP(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"/?callback=app.handle", http.StatusOK, "application/javascript", `/**/ typeof app.handle === 'function' && app.handle({"ok":true});`},
+		{"/", http.StatusOK, "application/json", `{"ok":true}`},
+		{"/?callback=alert(1)//", http.StatusBadRequest, "", ""},
+		{"/?callback=" + strings.Repeat("a", 200), http.StatusBadRequest, "", ""},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		c := context.NewContext(rr, httptest.NewRequest("GET", tt.target, nil))
+		err := c.JSONP(http.StatusOK, map[string]bool{"ok": true})
+
+		if tt.wantStatus == http.StatusBadRequest {
+			var he *context.HTTPError
+			if !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected a 400 HTTPError, got %v", tt.target, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: JSONP failed: %v", tt.target, err)
+		}
+		if rr.Header().Get("Content-Type") != tt.wantType || strings.TrimSpace(rr.Body.String()) != tt.wantBody {
+			t.Errorf("%s: unexpected response %s %q", tt.target, rr.Header().Get("Content-Type"), rr.Body.String())
+		}
+	}
+}
+
+func TestContext_XMLAndIndentedJSON(t *testing.T) {
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, httptest.NewRequest("GET", "/", nil))
+	if err := c.XML(http.StatusOK, product{ID: 1, Name: "Tea"}); err != nil {
+		t.Fatalf("XML failed: %v", err)
+	}
+	if rr.Header().Get("Content-Type") != "application/xml" || !strings.HasPrefix(rr.Body.String(), "<product><id>1</id>") {
+		t.Errorf("unexpected XML response: %s %q", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	c = context.NewContext(rr, httptest.NewRequest("GET", "/", nil))
+	if err := c.IndentedJSON(http.StatusOK, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("IndentedJSON failed: %v", err)
+	}
+	if rr.Body.String() != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("unexpected indented JSON: %q", rr.Body.String())
+	}
+}
@@ -6,12 +6,9 @@ import (
 	"io"
 	"mime"
 	"mime/multipart"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"reflect"
-	"strconv"
 	"strings"
 )
 
@@ -71,78 +68,35 @@ func (c *Context) GetHeader(key string) string {
 	return c.Request.Header.Get(key)
 }
 
-// RemoteIP returns the client's IP address.
-// IMPORTANT: This function trusts HTTP headers like X-Forwarded-For and X-Real-IP.
-// It should only be used when the server is behind a trusted reverse proxy that
-// sets these headers correctly. Otherwise, the IP can be easily spoofed =X
+// RemoteIP returns the client's IP address. The proxy header is only read
+// when the request comes from a trusted proxy (see SetProxyConfig), walking
+// X-Forwarded-For or Forwarded from the right and stopping at the first
+// address that is not a trusted proxy. Otherwise it is the peer's address.
 func (c *Context) RemoteIP() string {
-	if ip := c.GetHeader("X-Forwarded-For"); ip != "" {
-		return strings.Split(ip, ",")[0]
-	}
-	if ip := c.GetHeader("X-Real-IP"); ip != "" {
-		return ip
+	if c.fromTrustedProxy() {
+		if ip := c.forwardedClientIP(); ip != "" {
+			return ip
+		}
 	}
-	ip, _, _ := net.SplitHostPort(c.Request.RemoteAddr)
-	return ip
+	return c.peerIP()
 }
 
 func (c *Context) BaseURL() string {
-	scheme := "http"
-	if c.Request.TLS != nil {
-		scheme = "https"
-	}
-	return scheme + "://" + c.Request.Host
+	return c.Protocol() + "://" + c.Hostname()
 }
 
 func (c *Context) BodyRaw() ([]byte, error) {
 	return io.ReadAll(c.Request.Body)
 }
 
-func (c *Context) QueryParser(out interface{}) error {
-	if err := c.Request.ParseForm(); err != nil {
-		return err
-	}
-
-	val := reflect.ValueOf(out)
-	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
-		return errors.New("QueryParser requires a pointer to a struct")
-	}
-
-	elem := val.Elem()
-	typ := elem.Type()
-
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		tag := field.Tag.Get("query")
-		if tag == "" {
-			continue
-		}
-
-		paramValue := c.Query(tag)
-		if paramValue == "" {
-			continue
-		}
-
-		fieldValue := elem.Field(i)
-		if fieldValue.CanSet() {
-			switch fieldValue.Kind() {
-			case reflect.String:
-				fieldValue.SetString(paramValue)
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				if intVal, err := strconv.ParseInt(paramValue, 10, 64); err == nil {
-					fieldValue.SetInt(intVal)
-				}
-			case reflect.Bool:
-				if boolVal, err := strconv.ParseBool(paramValue); err == nil {
-					fieldValue.SetBool(boolVal)
-				}
-			}
+// Hostname returns the requested host. Behind a trusted proxy it comes from
+// the Forwarded or X-Forwarded-Host header.
+func (c *Context) Hostname() string {
+	if c.fromTrustedProxy() {
+		if host := c.forwardedValue("host", "X-Forwarded-Host"); host != "" {
+			return host
 		}
 	}
-	return nil
-}
-
-func (c *Context) Hostname() string {
 	return c.Request.Host
 }
 
@@ -163,7 +117,15 @@ func (c *Context) Is(extension string) bool {
 	return strings.HasPrefix(contentType, mimeType)
 }
 
+// Protocol returns "http" or "https". Behind a trusted proxy it comes from
+// the Forwarded or X-Forwarded-Proto header.
 func (c *Context) Protocol() string {
+	if c.fromTrustedProxy() {
+		switch proto := strings.ToLower(c.forwardedValue("proto", "X-Forwarded-Proto")); proto {
+		case "http", "https":
+			return proto
+		}
+	}
 	if c.Request.TLS != nil {
 		return "https"
 	}
@@ -180,5 +142,8 @@ func (c *Context) BindJSON(i interface{}) error {
 	// For security, you might want to disallow unknown fields to prevent
 	// unexpected data from being processed !
 	// decoder.DisallowUnknownFields()
-	return decoder.Decode(i)
+	if err := decoder.Decode(i); err != nil {
+		return err
+	}
+	return validated(i, "json", isBodyField)
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -62,23 +63,56 @@ func TestBindJSON(t *testing.T) {
 
 func TestRemoteIP(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
-
+	req.RemoteAddr = "10.0.0.2:12345"
 	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
 	ctx, _ := newTestContext(req)
+
+	// Without trusted proxies the headers are ignored.
+	if ip := ctx.RemoteIP(); ip != "10.0.0.2" {
+		t.Errorf("expected '10.0.0.2', got '%s'", ip)
+	}
+
+	ctx.SetProxyConfig(trustedProxies(t, "", "10.0.0.0/8"))
+	if ip := ctx.RemoteIP(); ip != "2.2.2.2" {
+		t.Errorf("expected the closest untrusted hop '2.2.2.2', got '%s'", ip)
+	}
+
+	req.Header.Set("X-Forwarded-For", "1.1.1.1 , 10.0.0.7")
 	if ip := ctx.RemoteIP(); ip != "1.1.1.1" {
-		t.Errorf("expected '1.1.1.1', got '%s'", ip)
+		t.Errorf("expected trusted hops to be skipped, got '%s'", ip)
+	}
+
+	req.Header.Set("X-Forwarded-For", "not-an-ip, 10.0.0.7")
+	if ip := ctx.RemoteIP(); ip != "10.0.0.2" {
+		t.Errorf("expected the peer for an invalid hop, got '%s'", ip)
 	}
 
-	req.Header.Del("X-Forwarded-For")
+	req.RemoteAddr = "4.4.4.4:12345"
+	if ip := ctx.RemoteIP(); ip != "4.4.4.4" {
+		t.Errorf("expected headers from an untrusted peer to be ignored, got '%s'", ip)
+	}
+
+	req.RemoteAddr = "10.0.0.2:12345"
 	req.Header.Set("X-Real-IP", "3.3.3.3")
+	ctx.SetProxyConfig(trustedProxies(t, "X-Real-IP", "10.0.0.0/8"))
 	if ip := ctx.RemoteIP(); ip != "3.3.3.3" {
 		t.Errorf("expected '3.3.3.3', got '%s'", ip)
 	}
+}
 
-	req.Header.Del("X-Real-IP")
-	req.RemoteAddr = "4.4.4.4:12345"
-	if ip := ctx.RemoteIP(); ip != "4.4.4.4" {
-		t.Errorf("expected '4.4.4.4', got '%s'", ip)
+func TestRemoteIP_Forwarded(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.2:12345"
+	req.Header.Add("Forwarded", `for=192.0.2.60;proto=http;host=evil.com`)
+	req.Header.Add("Forwarded", `for="[2001:db8::17]:4711";proto=https;host=example.com, for=10.0.0.9`)
+	ctx, _ := newTestContext(req)
+	ctx.SetProxyConfig(trustedProxies(t, "Forwarded", "10.0.0.0/8"))
+
+	if ip := ctx.RemoteIP(); ip != "2001:db8::17" {
+		t.Errorf("expected '2001:db8::17', got '%s'", ip)
+	}
+	if url := ctx.BaseURL(); url != "https://example.com" {
+		t.Errorf("expected 'https://example.com', got '%s'", url)
 	}
 }
 
@@ -94,6 +128,44 @@ func TestBaseURL(t *testing.T) {
 	if url := ctx.BaseURL(); url != "https://example.com" {
 		t.Errorf("expected 'https://example.com', got '%s'", url)
 	}
+
+	req.TLS = nil
+	req.RemoteAddr = "10.0.0.2:12345"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.Header.Set("X-Forwarded-Host", "api.example.com")
+	if url := ctx.BaseURL(); url != "http://example.com" {
+		t.Errorf("expected forwarded headers to be ignored, got '%s'", url)
+	}
+
+	ctx.SetProxyConfig(trustedProxies(t, "", "10.0.0.2"))
+	if url := ctx.BaseURL(); url != "https://api.example.com" {
+		t.Errorf("expected 'https://api.example.com', got '%s'", url)
+	}
+	if host := ctx.Hostname(); host != "api.example.com" {
+		t.Errorf("expected 'api.example.com', got '%s'", host)
+	}
+
+	req.Header.Set("X-Forwarded-Proto", "http, https")
+	req.Header.Set("X-Forwarded-Host", "evil.com, api.example.com")
+	if url := ctx.BaseURL(); url != "https://api.example.com" {
+		t.Errorf("expected the values added by the trusted proxy, got '%s'", url)
+	}
+}
+
+func trustedProxies(t *testing.T, header string, cidrs ...string) *ProxyConfig {
+	t.Helper()
+	config := &ProxyConfig{Header: header}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			cidr += "/32"
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.TrustedProxies = append(config.TrustedProxies, network)
+	}
+	return config
 }
 
 func TestQueryParser(t *testing.T) {
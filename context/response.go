@@ -2,12 +2,14 @@ package context
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"log"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -18,6 +20,47 @@ func (c *Context) JSON(code int, obj interface{}) error {
 	return json.NewEncoder(c.Writer).Encode(obj)
 }
 
+// IndentedJSON writes obj as JSON indented for reading.
+func (c *Context) IndentedJSON(code int, obj interface{}) error {
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(code)
+	encoder := json.NewEncoder(c.Writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(obj)
+}
+
+// XML writes obj as XML.
+func (c *Context) XML(code int, obj interface{}) error {
+	c.Writer.Header().Set("Content-Type", "application/xml")
+	c.Writer.WriteHeader(code)
+	return xml.NewEncoder(c.Writer).Encode(obj)
+}
+
+// jsonpCallback matches the JavaScript identifiers, possibly dotted, that
+// JSONP accepts as callback names.
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+// JSONP writes obj as a call to the function named by the "callback" query
+// parameter, or as plain JSON without one. Callback names other than
+// JavaScript identifiers are rejected with a 400 *HTTPError.
+func (c *Context) JSONP(code int, obj interface{}) error {
+	callback := c.Query("callback")
+	if callback == "" {
+		return c.JSON(code, obj)
+	}
+	if len(callback) > 128 || !jsonpCallback.MatchString(callback) {
+		return NewHTTPError(http.StatusBadRequest, "invalid JSONP callback")
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	c.Writer.Header().Set("X-Content-Type-Options", "nosniff")
+	// The comment stops the response from being read as a Flash file.
+	body := "/**/ typeof " + callback + " === 'function' && " + callback + "(" + string(data) + ");"
+	return c.Data(code, "application/javascript", []byte(body))
+}
+
 func (c *Context) Text(code int, text string) error {
 	c.Writer.Header().Set("Content-Type", "text/plain")
 	c.Writer.WriteHeader(code)
@@ -53,11 +96,14 @@ func (c *Context) SetCookie(cookie *http.Cookie) {
 	http.SetCookie(c.Writer, cookie)
 }
 
-// error is a helper for handling internal server errors.
-// error handling in Go. ..
+// Error passes err to the app's ErrorHandler, or to DefaultErrorHandler
+// when none is set. Use an *HTTPError to control the status code.
 func (c *Context) Error(err error) {
-	log.Println("Error:", err)
-	http.Error(c.Writer, "Internal Server Error", http.StatusInternalServerError)
+	if c.errorHandler != nil {
+		c.errorHandler(c, err)
+		return
+	}
+	DefaultErrorHandler(c, err)
 }
 
 func (c *Context) ClearCookie(name string) {
@@ -0,0 +1,83 @@
+package context
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// StatusCoder can be implemented by the responses of typed handlers to
+// choose their status code. Responses default to 200 OK.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Handle adapts a typed function to a HandlerFunc. The request is bound
+// into a Req from the route parameters, the query string and the body (see
+// Bind), and validated. The Resp returned is encoded by Render in the format
+// the client accepts; a nil response sends 204 No Content.
+//
+// Binding and validation failures, as well as errors returned by fn, go
+// through the app's ErrorHandler.
+func Handle[Req, Resp any](fn func(*Context, Req) (Resp, error)) HandlerFunc {
+	return func(c *Context) {
+		req, err := bindTyped[Req](c)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		resp, err := fn(c, req)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.respond(resp)
+	}
+}
+
+// bindTyped creates a Req and binds the request into it. A pointer type is
+// allocated so the handler always receives a value.
+func bindTyped[Req any](c *Context) (Req, error) {
+	var req Req
+	target := interface{}(&req)
+	if t := reflect.TypeOf(req); t != nil && t.Kind() == reflect.Ptr {
+		ptr := reflect.New(t.Elem())
+		reflect.ValueOf(&req).Elem().Set(ptr)
+		target = req
+	}
+	return req, c.bindRequest(target)
+}
+
+// bindRequest binds out from the route parameters, the query string and,
+// when there is one, the body. Without a body, the rules of the body fields
+// still run so missing required fields are reported.
+func (c *Context) bindRequest(out interface{}) error {
+	if indirectType(reflect.TypeOf(out).Elem()).Kind() == reflect.Struct {
+		if err := c.BindParams(out); err != nil {
+			return err
+		}
+		if err := c.BindQuery(out); err != nil {
+			return err
+		}
+	}
+	if c.hasBody() {
+		return c.Bind(out)
+	}
+	return validated(out, "json", isBodyField)
+}
+
+// respond encodes a typed handler's response.
+func (c *Context) respond(resp interface{}) {
+	rv := reflect.ValueOf(resp)
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		c.Writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	code := http.StatusOK
+	if sc, ok := resp.(StatusCoder); ok {
+		code = sc.StatusCode()
+	}
+	if err := c.Render(code, resp); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,435 @@
+package context
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationFunc reports whether value satisfies a rule. param is the text
+// after "=" in the tag, e.g. "64" for max=64.
+type ValidationFunc func(value reflect.Value, param string) bool
+
+// ValidationError lists the fields that failed their `validate` rules.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Field + " " + f.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) FieldErrors() []FieldError {
+	return e.Fields
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	alphaPattern    = regexp.MustCompile(`^[a-zA-Z]+$`)
+	alnumPattern    = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+	numericPattern  = regexp.MustCompile(`^[-+]?[0-9]+(\.[0-9]+)?$`)
+	validationRules = struct {
+		sync.RWMutex
+		funcs map[string]ValidationFunc
+		// bounds are the built-in rules taking a number parameter.
+		bounds map[string]bool
+	}{bounds: map[string]bool{
+		"min": true, "max": true, "len": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	}, funcs: map[string]ValidationFunc{
+		"min":      compareRule(func(n, p float64) bool { return n >= p }),
+		"max":      compareRule(func(n, p float64) bool { return n <= p }),
+		"len":      compareRule(func(n, p float64) bool { return n == p }),
+		"gt":       compareRule(func(n, p float64) bool { return n > p }),
+		"gte":      compareRule(func(n, p float64) bool { return n >= p }),
+		"lt":       compareRule(func(n, p float64) bool { return n < p }),
+		"lte":      compareRule(func(n, p float64) bool { return n <= p }),
+		"oneof":    validateOneOf,
+		"email":    validateEmail,
+		"url":      validateURL,
+		"uuid":     patternRule(uuidPattern),
+		"alpha":    patternRule(alphaPattern),
+		"alphanum": patternRule(alnumPattern),
+		"numeric":  patternRule(numericPattern),
+	}}
+)
+
+// RegisterValidation adds a rule usable in `validate` tags, or replaces an
+// existing one. It panics if the name is reserved or fn is nil.
+func RegisterValidation(name string, fn ValidationFunc) {
+	switch name {
+	case "", "required", "omitempty", "dive":
+		panic(fmt.Sprintf("context: invalid validation rule name %q", name))
+	}
+	if fn == nil {
+		panic(fmt.Sprintf("context: nil validation func for rule %q", name))
+	}
+	validationRules.Lock()
+	defer validationRules.Unlock()
+	validationRules.funcs[name] = fn
+	delete(validationRules.bounds, name)
+	// Tags parsed before may use the rule, or a rule it replaces.
+	ruleCache.Range(func(t, _ interface{}) bool {
+		ruleCache.Delete(t)
+		return true
+	})
+}
+
+// rule is a parsed entry of a `validate` tag, such as max=64.
+type rule struct {
+	name  string
+	param string
+	fn    ValidationFunc
+}
+
+// structRules are the parsed rules of the fields of a struct type, or the
+// error found in its tags.
+type structRules struct {
+	fields [][]rule
+	err    error
+}
+
+// ruleCache maps struct types to their *structRules, so tags are parsed
+// once per type.
+var ruleCache sync.Map
+
+func rulesOf(t reflect.Type) ([][]rule, error) {
+	if cached, ok := ruleCache.Load(t); ok {
+		sr := cached.(*structRules)
+		return sr.fields, sr.err
+	}
+	sr := &structRules{fields: make([][]rule, t.NumField())}
+	for i := range sr.fields {
+		field := t.Field(i)
+		rules, err := parseRules(field.Tag.Get("validate"))
+		if err != nil {
+			sr.err = fmt.Errorf("context: field %s of %s: %v", field.Name, t, err)
+			break
+		}
+		sr.fields[i] = rules
+	}
+	ruleCache.Store(t, sr)
+	return sr.fields, sr.err
+}
+
+// parseRules parses a `validate` tag, rejecting unknown rules and invalid
+// parameters of the bound rules.
+func parseRules(tag string) ([]rule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+	validationRules.RLock()
+	defer validationRules.RUnlock()
+
+	var rules []rule
+	for _, entry := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		switch name {
+		case "":
+			continue
+		case "required", "omitempty", "dive":
+			rules = append(rules, rule{name: name, param: param})
+			continue
+		}
+		fn, ok := validationRules.funcs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown validation rule %q", name)
+		}
+		if validationRules.bounds[name] {
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				return nil, fmt.Errorf("invalid parameter %q for validation rule %q", param, name)
+			}
+		}
+		rules = append(rules, rule{name: name, param: param, fn: fn})
+	}
+	return rules, nil
+}
+
+// Validate checks the `validate` tags of the struct pointed to by v,
+// including nested structs and the structs in slices. It returns a
+// *ValidationError keyed by the fields' json names, or nil.
+//
+// Rules are separated by commas:
+//
+//   - required: the value is not zero, nil or empty
+//   - omitempty: skip the other rules when the value is empty
+//   - min, max, len, gt, gte, lt, lte: bounds on numbers, on the length of
+//     strings (in characters) and on the size of slices and maps
+//   - oneof=a b: the value is one of the space-separated options
+//   - email, url, uuid, alpha, alphanum, numeric: string formats
+//   - dive: apply the rules that follow to each element of a slice or map
+//
+// Unknown rules and invalid rule parameters are reported by a plain error,
+// not a *ValidationError, since they are mistakes in the tags.
+func Validate(v interface{}) error {
+	return validate(v, "json", nil)
+}
+
+// validate checks the fields of v accepted by include, naming them after tag.
+func validate(v interface{}, tag string, include func(reflect.StructField) bool) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	val := &validator{tag: tag, include: include}
+	val.validateStruct(rv, "")
+	if val.err != nil {
+		return val.err
+	}
+	if len(val.errs) > 0 {
+		return &ValidationError{Fields: val.errs}
+	}
+	return nil
+}
+
+type validator struct {
+	tag     string
+	include func(reflect.StructField) bool
+	errs    []FieldError
+	err     error
+}
+
+func (val *validator) validateStruct(v reflect.Value, prefix string) {
+	t := v.Type()
+	fieldRules, err := rulesOf(t)
+	if err != nil {
+		val.err = err
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := tagName(field, val.tag)
+		if name == "-" {
+			continue
+		}
+		// Embedded structs are checked before skipping unexported fields,
+		// as their exported fields are promoted and bound.
+		if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+			val.validateValue(v.Field(i), nil, strings.TrimSuffix(prefix, "."))
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if val.include != nil && !val.include(field) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		val.validateValue(v.Field(i), fieldRules[i], prefix+name)
+	}
+}
+
+// validateValue applies rules to v, then validates the structs it holds.
+func (val *validator) validateValue(v reflect.Value, rules []rule, key string) {
+	for i, r := range rules {
+		switch r.name {
+		case "omitempty":
+			if !hasValue(v) {
+				return
+			}
+			continue
+		case "required":
+			if !hasValue(v) {
+				val.fail(key, r.name, r.param, v)
+				return
+			}
+			continue
+		case "dive":
+			val.validateElements(v, rules[i+1:], key)
+			return
+		}
+
+		target := v
+		for target.Kind() == reflect.Ptr || target.Kind() == reflect.Interface {
+			if target.IsNil() {
+				return
+			}
+			target = target.Elem()
+		}
+		if !r.fn(target, r.param) {
+			val.fail(key, r.name, r.param, target)
+			return
+		}
+	}
+	val.validateElements(v, nil, key)
+}
+
+// validateElements validates the struct in v or, for slices, arrays and
+// maps, each element with rules.
+func (val *validator) validateElements(v reflect.Value, rules []rule, key string) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if isNestedStruct(v.Type()) {
+			if key != "" {
+				key += "."
+			}
+			val.validateStruct(v, key)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			val.validateValue(v.Index(i), rules, fmt.Sprintf("%s[%d]", key, i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			val.validateValue(iter.Value(), rules, fmt.Sprintf("%s[%v]", key, iter.Key()))
+		}
+	}
+}
+
+func (val *validator) fail(key, rule, param string, v reflect.Value) {
+	val.errs = append(val.errs, FieldError{Field: key, Rule: rule, Message: ruleMessage(rule, param, v)})
+}
+
+func ruleMessage(rule, param string, v reflect.Value) string {
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	switch rule {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + param + unit
+	case "max":
+		return "must be at most " + param + unit
+	case "len":
+		return "must be exactly " + param + unit
+	case "gt":
+		return "must be greater than " + param + unit
+	case "gte":
+		return "must be at least " + param + unit
+	case "lt":
+		return "must be less than " + param + unit
+	case "lte":
+		return "must be at most " + param + unit
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid":
+		return "must be a valid UUID"
+	case "alpha":
+		return "must contain only letters"
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "numeric":
+		return "must be numeric"
+	}
+	return "failed the " + rule + " rule"
+}
+
+// hasValue reports whether v is set: non-nil, non-empty and non-zero.
+func hasValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return !v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() > 0
+	case reflect.Invalid:
+		return false
+	}
+	return !v.IsZero()
+}
+
+// size returns the number compared by the bound rules: the value of
+// numbers, the length of strings in characters and the size of collections.
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compareRule(compare func(n, param float64) bool) ValidationFunc {
+	return func(v reflect.Value, param string) bool {
+		p, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false
+		}
+		n, ok := size(v)
+		return ok && compare(n, p)
+	}
+}
+
+func patternRule(pattern *regexp.Regexp) ValidationFunc {
+	return func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && pattern.MatchString(v.String())
+	}
+}
+
+func validateOneOf(v reflect.Value, param string) bool {
+	var value string
+	switch v.Kind() {
+	case reflect.String:
+		value = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func validateURL(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(v.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
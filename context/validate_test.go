@@ -0,0 +1,222 @@
+package context_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arthurlch/goryu/context"
+)
+
+type item struct {
+	SKU      string `json:"sku" validate:"required,alphanum"`
+	Quantity int    `json:"quantity" validate:"gte=1,lte=10"`
+}
+
+type order struct {
+	Email    string            `json:"email" validate:"required,email"`
+	Name     string            `json:"name" validate:"min=2,max=8"`
+	Status   string            `json:"status" validate:"oneof=new paid"`
+	Website  string            `json:"website" validate:"omitempty,url"`
+	ID       string            `json:"id" validate:"omitempty,uuid"`
+	Items    []item            `json:"items" validate:"required,max=3"`
+	Tags     []string          `json:"tags" validate:"dive,alpha"`
+	Labels   map[string]string `json:"labels" validate:"dive,max=3"`
+	Shipping *struct {
+		Zip string `json:"zip" validate:"len=5,numeric"`
+	} `json:"shipping"`
+	Note string `json:"-" validate:"required"`
+}
+
+func validOrder() order {
+	return order{
+		Email:  "ada@example.com",
+		Name:   "Ada",
+		Status: "paid",
+		Items:  []item{{SKU: "a1", Quantity: 2}},
+		Tags:   []string{"gift"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := validOrder()
+	if err := context.Validate(&o); err != nil {
+		t.Fatalf("expected a valid order, got %v", err)
+	}
+
+	o.Email = "Ada <ada@example.com>"
+	o.Name = "Ä"
+	o.Status = "lost"
+	o.Website = "example.com"
+	o.ID = "not-a-uuid"
+	o.Items = append(o.Items, item{Quantity: 11})
+	o.Tags = append(o.Tags, "x1")
+	o.Labels = map[string]string{"k": "long"}
+	o.Shipping = &struct {
+		Zip string `json:"zip" validate:"len=5,numeric"`
+	}{Zip: "7500A"}
+
+	err := context.Validate(&o)
+	var ve *context.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range ve.Fields {
+		got[f.Field] = f.Rule
+	}
+	want := map[string]string{
+		"email":             "email",
+		"name":              "min",
+		"status":            "oneof",
+		"website":           "url",
+		"id":                "uuid",
+		"items[1].sku":      "required",
+		"items[1].quantity": "lte",
+		"tags[1]":           "alpha",
+		"labels[k]":         "max",
+		"shipping.zip":      "numeric",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected field errors:\n got  %v\n want %v", got, want)
+	}
+	if ve.Fields[1].Message != "must be at least 2 characters" {
+		t.Errorf("unexpected message: %s", ve.Fields[1].Message)
+	}
+}
+
+func TestValidate_Required(t *testing.T) {
+	var o order
+	err := context.Validate(&o)
+	var ve *context.ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if ve.Fields[0].Field != "email" || ve.Fields[0].Message != "is required" {
+		t.Errorf("unexpected first field error: %+v", ve.Fields[0])
+	}
+	for _, f := range ve.Fields {
+		if f.Field == "Note" {
+			t.Error("expected field tagged json:\"-\" to be skipped")
+		}
+	}
+}
+
+type audit struct {
+	CreatedBy string `json:"created_by" validate:"required"`
+}
+
+func TestValidate_UnexportedEmbedded(t *testing.T) {
+	var withValue struct{ audit }
+	var withPointer struct{ *audit }
+
+	for _, v := range []interface{}{&withValue, &struct{ *audit }{&audit{}}} {
+		var ve *context.ValidationError
+		if err := context.Validate(v); !errors.As(err, &ve) || ve.Fields[0].Field != "created_by" {
+			t.Errorf("expected the promoted field to be validated, got %v", err)
+		}
+	}
+	if err := context.Validate(&withPointer); err != nil {
+		t.Errorf("expected a nil embedded pointer to be skipped, got %v", err)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	context.RegisterValidation("even", func(v reflect.Value, _ string) bool {
+		return v.Int()%2 == 0
+	})
+
+	type pair struct {
+		Count *int `json:"count" validate:"even"`
+	}
+	three := 3
+	err := context.Validate(&pair{Count: &three})
+	var ve *context.ValidationError
+	if !errors.As(err, &ve) || ve.Fields[0].Rule != "even" || ve.Fields[0].Message != "failed the even rule" {
+		t.Errorf("expected the custom rule to fail, got %v", err)
+	}
+	if err := context.Validate(&pair{}); err != nil {
+		t.Errorf("expected a nil pointer to skip the rule, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when registering a reserved rule")
+		}
+	}()
+	context.RegisterValidation("required", func(reflect.Value, string) bool { return true })
+}
+
+func TestValidate_UnknownRule(t *testing.T) {
+	type typo struct {
+		Name string `validate:"requird"`
+	}
+	for _, v := range []interface{}{&typo{Name: "x"}, &struct {
+		Age int `validate:"max=abc"`
+	}{}} {
+		err := context.Validate(v)
+		var ve *context.ValidationError
+		if err == nil || errors.As(err, &ve) {
+			t.Errorf("expected a plain error for an invalid tag, got %v", err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/?name=x", nil)
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, req)
+	c.Error(c.BindQuery(&struct {
+		Name string `query:"name" validate:"requird"`
+	}{}))
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected a 500 for an invalid tag, got %d", rr.Code)
+	}
+}
+
+func TestContext_Bind_Validation(t *testing.T) {
+	type request struct {
+		ID    int    `param:"id" validate:"gt=0"`
+		Page  int    `query:"page" validate:"min=1"`
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	req := httptest.NewRequest("POST", "/users/0?page=1", strings.NewReader(`{"email":"nope"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	c := context.NewContext(rr, req)
+	c.Params = append(c.Params, context.Param{Key: "id", Value: "0"})
+
+	var r request
+	if err := c.BindQuery(&r); err != nil {
+		t.Fatalf("expected BindQuery to only validate query fields, got %v", err)
+	}
+
+	err := c.Bind(&r)
+	var he *context.HTTPError
+	if !errors.As(err, &he) || he.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %v", err)
+	}
+	c.Error(err)
+
+	var body struct {
+		Error  string               `json:"error"`
+		Fields []context.FieldError `json:"fields"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if rr.Code != http.StatusUnprocessableEntity || len(body.Fields) != 1 {
+		t.Fatalf("expected one field error with status 422, got %d %+v", rr.Code, body)
+	}
+	if f := body.Fields[0]; f.Field != "email" || f.Rule != "email" {
+		t.Errorf("unexpected field error: %+v", f)
+	}
+
+	if err := c.BindParams(&r); !errors.As(err, &he) || he.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for the param, got %v", err)
+	}
+}
@@ -1,96 +1,84 @@
 package goryu
 
 import (
-	"fmt"
 	"net/http"
 
+	"github.com/arthurlch/goryu/app"
 	"github.com/arthurlch/goryu/context"
-	"github.com/arthurlch/goryu/router"
 )
 
 type Context = context.Context
 type HandlerFunc = context.HandlerFunc
 type Middleware = context.Middleware
+type HandlersChain = context.HandlersChain
+type HandlerFuncE = context.HandlerFuncE
+type MiddlewareE = context.MiddlewareE
+type ErrorHandler = context.ErrorHandler
+type HTTPError = context.HTTPError
+type ValidationFunc = context.ValidationFunc
+type StatusCoder = context.StatusCoder
+type Renderer = context.Renderer
+type RendererFunc = context.RendererFunc
 
-type Config struct {
-	// AppName is the name of the application.
-	// Default: ""
-	AppName string
-	// ServerHeader is the value of the "Server" header.
-	// Default: ""
-	ServerHeader string
-	// StrictRouting enables strict routing.
-	// Default: false
-	StrictRouting bool
-	// CaseSensitive enables case-sensitive routing.
-	// Default: false
-	CaseSensitive bool
-	// DisableStartupMessage disables the startup message.
-	// Default: false
-	DisableStartupMessage bool
+// WrapHandler adapts an http.Handler to a HandlerFunc.
+func WrapHandler(handler http.Handler) HandlerFunc {
+	return context.WrapHandler(handler)
 }
 
-type App struct {
-	Router      *router.Router
-	middlewares []Middleware
-	Config      Config
+// WrapHTTPMiddleware adapts a net/http middleware to a Middleware.
+func WrapHTTPMiddleware(middleware func(http.Handler) http.Handler) Middleware {
+	return context.WrapHTTPMiddleware(middleware)
 }
 
-func New(config ...Config) *App {
-	cfg := Config{
-		AppName:      "",
-		ServerHeader: "",
-	}
-
-	if len(config) > 0 {
-		cfg = config[0]
-	}
+// ToHTTPMiddleware adapts a Middleware to net/http.
+func ToHTTPMiddleware(middleware Middleware) func(http.Handler) http.Handler {
+	return context.ToHTTPMiddleware(middleware)
+}
 
-	return &App{
-		Router:      router.New(),
-		middlewares: make([]Middleware, 0),
-		Config:      cfg,
-	}
+// FromRequest returns the Context attached to a request by the adapters.
+func FromRequest(r *http.Request) (*Context, bool) {
+	return context.FromRequest(r)
 }
 
-func (app *App) Use(middleware Middleware) {
-	app.middlewares = append(app.middlewares, middleware)
+// NewHTTPError creates an HTTPError for the given status code.
+func NewHTTPError(code int, message ...string) *HTTPError {
+	return context.NewHTTPError(code, message...)
 }
 
-func (app *App) applyMiddleware(handler HandlerFunc) HandlerFunc {
-	appliedHandler := handler
-	for i := len(app.middlewares) - 1; i >= 0; i-- {
-		appliedHandler = app.middlewares[i](appliedHandler)
-	}
-	return appliedHandler
+// Validate checks the `validate` tags of the struct pointed to by v.
+func Validate(v interface{}) error {
+	return context.Validate(v)
 }
 
-func (app *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if app.Config.ServerHeader != "" {
-		w.Header().Set("Server", app.Config.ServerHeader)
-	}
-	if err := req.ParseForm(); err != nil {
-		http.Error(w, "Failed to parse Form Data", http.StatusBadRequest)
-		return
-	}
-	app.Router.ServeHTTP(w, req)
+// RegisterValidation adds a rule usable in `validate` tags.
+func RegisterValidation(name string, fn ValidationFunc) {
+	context.RegisterValidation(name, fn)
 }
 
-func (app *App) GET(path string, handler HandlerFunc) {
-	app.Router.GET(path, app.applyMiddleware(handler))
+// RegisterRenderer makes Context.Render able to respond with mediaType.
+func RegisterRenderer(mediaType string, r Renderer) {
+	context.RegisterRenderer(mediaType, r)
 }
 
-func (app *App) POST(path string, handler HandlerFunc) {
-	app.Router.POST(path, app.applyMiddleware(handler))
+// Handle adapts a typed function to a HandlerFunc that binds and validates
+// a Req, then encodes the Resp returned.
+func Handle[Req, Resp any](fn func(*Context, Req) (Resp, error)) HandlerFunc {
+	return context.Handle(fn)
 }
 
-func (app *App) Run(addr string) error {
-	if !app.Config.DisableStartupMessage {
-		appName := "Goryu"
-		if app.Config.AppName != "" {
-			appName = app.Config.AppName
-		}
-		fmt.Printf("%s is running on %s\n", appName, addr)
-	}
-	return http.ListenAndServe(addr, app)
+// App is the application type.
+//
+// Deprecated: use app.App, which this is an alias of.
+type App = app.App
+
+// Config configures an App.
+//
+// Deprecated: use app.Config, which this is an alias of.
+type Config = app.Config
+
+// New creates an App.
+//
+// Deprecated: use app.New.
+func New(config ...Config) *App {
+	return app.New(config...)
 }
@@ -0,0 +1,106 @@
+package goryu_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/arthurlch/goryu"
+	"github.com/arthurlch/goryu/app"
+	"github.com/arthurlch/goryu/middleware/requestid"
+)
+
+func TestNew_ReturnsApp(t *testing.T) {
+	var a *app.App = goryu.New(goryu.Config{ServerHeader: "goryu"})
+	a.Use(requestid.New())
+	a.GET("/ping", func(c *goryu.Context) {
+		id, _ := c.Get("requestid")
+		_ = c.Text(http.StatusOK, id.(string))
+	})
+
+	rr := httptest.NewRecorder()
+	a.ServeHTTP(rr, httptest.NewRequest("GET", "/ping", nil))
+	if rr.Header().Get("Server") != "goryu" {
+		t.Error("expected the Server header from the config")
+	}
+	if rr.Body.String() == "" || rr.Body.String() != rr.Header().Get("X-Request-ID") {
+		t.Errorf("expected the request ID in the body, got '%s'", rr.Body.String())
+	}
+}
+
+type createUserRequest struct {
+	OrgID  int    `param:"org" json:"-"`
+	Notify bool   `query:"notify" json:"-"`
+	Name   string `json:"name" xml:"name" validate:"required"`
+}
+
+type createUserResponse struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	OrgID   int      `json:"org_id" xml:"org_id"`
+	Name    string   `json:"name" xml:"name"`
+	Notify  bool     `json:"notify" xml:"notify"`
+}
+
+func (createUserResponse) StatusCode() int { return http.StatusCreated }
+
+func TestHandle(t *testing.T) {
+	a := goryu.New()
+	a.POST("/orgs/:org/users", goryu.Handle(func(c *goryu.Context, req createUserRequest) (createUserResponse, error) {
+		if req.Name == "taken" {
+			return createUserResponse{}, goryu.NewHTTPError(http.StatusConflict)
+		}
+		return createUserResponse{OrgID: req.OrgID, Name: req.Name, Notify: req.Notify}, nil
+	}))
+	a.DELETE("/users/:id", goryu.Handle(func(c *goryu.Context, req *struct {
+		ID int `param:"id" validate:"gt=0"`
+	}) (*struct{}, error) {
+		return nil, nil
+	}))
+	a.GET("/stats", goryu.Handle(func(c *goryu.Context, _ struct{}) (map[string]int, error) {
+		return map[string]int{"users": 2}, nil
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{"json", "POST", "/orgs/7/users?notify=true", `{"name":"ada"}`, "", http.StatusCreated, `{"org_id":7,"name":"ada","notify":true}`},
+		{"xml", "POST", "/orgs/7/users", `{"name":"ada"}`, "application/xml, application/json;q=0.5", http.StatusCreated, `<user><org_id>7</org_id><name>ada</name><notify>false</notify></user>`},
+		{"not acceptable", "POST", "/orgs/7/users", `{"name":"ada"}`, "text/csv", http.StatusNotAcceptable, ""},
+		{"invalid param", "POST", "/orgs/x/users", `{"name":"ada"}`, "", http.StatusBadRequest, ""},
+		{"validation", "POST", "/orgs/7/users", `{}`, "", http.StatusUnprocessableEntity, ""},
+		{"missing body", "POST", "/orgs/7/users", "", "", http.StatusUnprocessableEntity, ""},
+		{"handler error", "POST", "/orgs/7/users", `{"name":"taken"}`, "", http.StatusConflict, ""},
+		{"no content", "DELETE", "/users/3", "", "", http.StatusNoContent, ""},
+		{"pointer request", "DELETE", "/users/0", "", "", http.StatusUnprocessableEntity, ""},
+		{"browser map", "GET", "/stats", "", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", http.StatusOK, `{"users":2}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			a.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantBody != "" && strings.TrimSpace(rr.Body.String()) != tt.wantBody {
+				t.Errorf("expected body %s, got %s", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package basicauth
 
 import (
 	"encoding/base64"
-	"log"
 	"net/http"
 	"strings"
 
@@ -81,7 +80,5 @@ func New(config Config) goryu.Middleware {
 
 func unauthorized(c *goryu.Context, realm string) {
 	c.Writer.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
-	if err := c.Status(http.StatusUnauthorized).Text(http.StatusUnauthorized, "Unauthorized"); err != nil {
-		log.Printf("basicauth: could not send error response: %v", err)
-	}
+	c.Error(goryu.NewHTTPError(http.StatusUnauthorized))
 }
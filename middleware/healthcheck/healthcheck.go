@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,6 +18,8 @@ type HealthChecker struct {
 	livePath    string
 	readyPath   string
 	timeout     time.Duration
+	// notReady forces readiness to DOWN, e.g. while the server drains.
+	notReady atomic.Bool
 }
 
 func NewHealthChecker() *HealthChecker {
@@ -37,12 +40,22 @@ func (h *HealthChecker) AddReadinessCheck(name string, probe Probe) {
 	h.readyProbes[name] = probe
 }
 
+// SetReady reports readiness as DOWN without running the probes when ready
+// is false, so load balancers stop sending traffic before shutdown.
+func (h *HealthChecker) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
 func (h *HealthChecker) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case h.livePath:
 			h.runProbes(w, r, h.liveProbes)
 		case h.readyPath:
+			if h.notReady.Load() {
+				h.writeResponse(w, http.StatusServiceUnavailable, map[string]string{"status": "DOWN"})
+				return
+			}
 			h.runProbes(w, r, h.readyProbes)
 		default:
 			next.ServeHTTP(w, r)
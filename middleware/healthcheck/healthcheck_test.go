@@ -100,4 +100,25 @@ func TestHealthChecker(t *testing.T) {
 			t.Errorf("handler did not pass through: got %v want %v", status, http.StatusAccepted)
 		}
 	})
+
+	t.Run("readiness down", func(t *testing.T) {
+		t.Parallel()
+		health := NewHealthChecker()
+		health.AddReadinessCheck("passing-check", probeThatSucceeds)
+		handler := health.Middleware(nextHandler)
+
+		health.SetReady(false)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		if status := rr.Code; status != http.StatusServiceUnavailable {
+			t.Errorf("wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+		}
+
+		health.SetReady(true)
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("wrong status code: got %v want %v", status, http.StatusOK)
+		}
+	})
 }
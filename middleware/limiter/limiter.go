@@ -1,7 +1,6 @@
 package limiter
 
 import (
-	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -44,9 +43,7 @@ func New(config ...Config) goryu.Middleware {
 	}
 	if cfg.LimitReached == nil {
 		cfg.LimitReached = func(c *goryu.Context) {
-			if err := c.Status(http.StatusTooManyRequests).Text(http.StatusTooManyRequests, "Too Many Requests"); err != nil {
-				log.Printf("limiter: could not send error response: %v", err)
-			}
+			c.Error(goryu.NewHTTPError(http.StatusTooManyRequests))
 		}
 	}
 
@@ -39,17 +39,17 @@ func New(config ...Config) goryu.Middleware {
 						err = fmt.Errorf("%v", r)
 					}
 
-					log.Printf("Panic recovered: %v", err)
 					if cfg.EnableStackTrace {
 						log.Printf("Stack trace:\n%s", debug.Stack())
 					}
 
+					// The handlers after the one that panicked must not run.
+					c.Abort()
 					if c.Writer.Header().Get("Content-Type") == "" {
-						if jsonErr := c.JSON(http.StatusInternalServerError, map[string]string{
-							"error": "Internal Server Error",
-						}); jsonErr != nil {
-							log.Printf("recovery middleware: could not send error response: %v", jsonErr)
-						}
+						// The error handler logs the 500.
+						c.Error(goryu.NewHTTPError(http.StatusInternalServerError).Wrap(err))
+					} else {
+						log.Printf("Panic recovered: %v", err)
 					}
 				}
 			}()
@@ -61,6 +61,34 @@ func TestRecoveryMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("Stops the chain after a panicking middleware", func(t *testing.T) {
+		failing := func(next goryu.HandlerFunc) goryu.HandlerFunc {
+			return func(c *goryu.Context) {
+				panic("auth check failed")
+			}
+		}
+
+		req := httptest.NewRequest("GET", "/secret", nil)
+		ctx, rr := newTestContext(req)
+		ran := false
+		ctx.SetHandlers(goryu.HandlersChain{
+			context.FromMiddleware(recovery.New()),
+			context.FromMiddleware(failing),
+			func(c *goryu.Context) {
+				ran = true
+				_ = c.Text(http.StatusOK, "secret")
+			},
+		})
+		ctx.Next()
+
+		if ran || strings.Contains(rr.Body.String(), "secret") {
+			t.Errorf("expected the handler not to run, got '%s'", rr.Body.String())
+		}
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
+
 	t.Run("Skip middleware with Next", func(t *testing.T) {
 		config := recovery.Config{
 			Next: func(c *goryu.Context) bool {
@@ -0,0 +1,34 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/router"
+)
+
+// Register serves the document of r's routes at config.Path and its viewer
+// at config.UIPath. The document is generated on each request, so it
+// includes the routes registered later. Both routes are hidden from it.
+func Register(r *router.Router, config ...Config) {
+	cfg := Config{}
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	cfg.setDefaults()
+
+	r.GET(cfg.Path, func(c *context.Context) {
+		docConfig := cfg
+		if len(docConfig.Servers) == 0 && r.Prefix() != "" {
+			docConfig.Servers = []Server{{URL: r.Prefix()}}
+		}
+		_ = c.JSON(http.StatusOK, Generate(r, docConfig))
+	}).SetHidden()
+
+	if cfg.UIPath == "-" {
+		return
+	}
+	r.GET(cfg.UIPath, func(c *context.Context) {
+		_ = c.Data(http.StatusOK, "text/html; charset=utf-8", Viewer(cfg.Info.Title, r.Prefix()+cfg.Path))
+	}).SetHidden()
+}
@@ -0,0 +1,288 @@
+// Package openapi generates OpenAPI 3.1 documents from the routes of a
+// router.Router.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/arthurlch/goryu/router"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// Config configures the generated document and where it is served.
+type Config struct {
+	// Info describes the API.
+	// Default: Title "API", Version "1.0.0"
+	Info Info
+	// Servers lists the base URLs of the API.
+	// Default: nil, the paths are relative to the document
+	Servers []Server
+	// Path serves the JSON document.
+	// Default: "/openapi.json"
+	Path string
+	// UIPath serves the HTML viewer. "-" disables it.
+	// Default: "/docs"
+	UIPath string
+}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Info.Title == "" {
+		cfg.Info.Title = "API"
+	}
+	if cfg.Info.Version == "" {
+		cfg.Info.Version = "1.0.0"
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/openapi.json"
+	}
+	if cfg.UIPath == "" {
+		cfg.UIPath = "/docs"
+	}
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers,omitempty"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components,omitempty"`
+	Tags       []Tag                `json:"tags,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// PathItem holds the operations of a path, by lower-case method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []*Parameter        `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// methods are the HTTP methods an OpenAPI path item can describe.
+var methods = map[string]bool{
+	http.MethodGet: true, http.MethodPut: true, http.MethodPost: true, http.MethodDelete: true,
+	http.MethodOptions: true, http.MethodHead: true, http.MethodPatch: true, http.MethodTrace: true,
+}
+
+// Generate builds the document of the routes registered on r and on the
+// routers mounted under it, described by their Route.Doc. Routes registered
+// with ALL, hidden routes and routes using methods OpenAPI cannot describe
+// are left out.
+func Generate(r *router.Router, config Config) *Document {
+	config.setDefaults()
+	g := &generator{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       config.Info,
+			Servers:    config.Servers,
+			Paths:      make(map[string]*PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		schemas: newSchemas(),
+		tags:    make(map[string]bool),
+	}
+	g.addRouter(r, r.Prefix())
+	g.doc.Components.Schemas = g.schemas.components
+	sort.Slice(g.doc.Tags, func(i, j int) bool {
+		return g.doc.Tags[i].Name < g.doc.Tags[j].Name
+	})
+	return g.doc
+}
+
+type generator struct {
+	doc     *Document
+	schemas *schemas
+	tags    map[string]bool
+}
+
+func (g *generator) addRouter(r *router.Router, root string) {
+	prefix := strings.TrimPrefix(r.Prefix(), root)
+	for _, route := range r.Routes() {
+		if route.Doc.Hidden || !methods[route.Method] {
+			continue
+		}
+		for _, path := range openAPIPaths(prefix, route.Segments()) {
+			g.addOperation(route, path)
+		}
+	}
+	for _, sub := range r.Mounts() {
+		g.addRouter(sub, root)
+	}
+}
+
+func (g *generator) addOperation(route *router.Route, path pathTemplate) {
+	op := &Operation{
+		OperationID: route.Name,
+		Summary:     route.Doc.Summary,
+		Description: route.Doc.Description,
+		Tags:        route.Doc.Tags,
+		Deprecated:  route.Doc.Deprecated,
+		Responses:   make(map[string]Response),
+	}
+	for _, tag := range route.Doc.Tags {
+		if !g.tags[tag] {
+			g.tags[tag] = true
+			g.doc.Tags = append(g.doc.Tags, Tag{Name: tag})
+		}
+	}
+
+	var fields requestFields
+	if route.Doc.Request != nil {
+		fields = g.schemas.requestFields(route.Doc.Request)
+	}
+	for _, param := range path.params {
+		if schema, ok := fields.params["path"][param.Name]; ok {
+			param.Schema = schema
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	for _, in := range []string{"query", "header", "cookie"} {
+		for _, name := range fields.order[in] {
+			op.Parameters = append(op.Parameters, &Parameter{
+				Name:     name,
+				In:       in,
+				Required: fields.required[in][name],
+				Schema:   fields.params[in][name],
+			})
+		}
+	}
+	if fields.body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: fields.body}},
+		}
+	}
+
+	for code, t := range route.Doc.Responses {
+		response := Response{Description: http.StatusText(code)}
+		if t != nil {
+			response.Content = map[string]MediaType{"application/json": {Schema: g.schemas.schema(t)}}
+		}
+		op.Responses[strconv.Itoa(code)] = response
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["200"] = Response{Description: http.StatusText(http.StatusOK)}
+	}
+
+	item, ok := g.doc.Paths[path.path]
+	if !ok {
+		item = &PathItem{}
+		g.doc.Paths[path.path] = item
+	}
+	(*item)[strings.ToLower(route.Method)] = op
+}
+
+// pathTemplate is a route path in OpenAPI syntax with its parameters.
+type pathTemplate struct {
+	path   string
+	params []*Parameter
+}
+
+// openAPIPaths converts a route pattern such as "/users/:id<int>/*path" to
+// "/users/{id}/{path}". An optional last parameter gives a second path
+// without it, as the router registers both.
+func openAPIPaths(prefix string, segments []router.Segment) []pathTemplate {
+	var parts []string
+	var params []*Parameter
+	var paths []pathTemplate
+	for _, seg := range segments {
+		if seg.Param == "" {
+			parts = append(parts, seg.Static)
+			continue
+		}
+		if seg.Optional {
+			paths = append(paths, pathTemplate{
+				path:   joinPath(prefix, parts),
+				params: append([]*Parameter(nil), params...),
+			})
+		}
+		parts = append(parts, "{"+seg.Param+"}")
+		params = append(params, &Parameter{
+			Name:     seg.Param,
+			In:       "path",
+			Required: true,
+			Schema:   constraintSchema(seg.Constraint),
+		})
+	}
+	return append(paths, pathTemplate{path: joinPath(prefix, parts), params: params})
+}
+
+func joinPath(prefix string, parts []string) string {
+	path := "/" + strings.Join(parts, "/")
+	if prefix != "" && path == "/" {
+		return prefix
+	}
+	return prefix + path
+}
+
+// constraintSchema returns the schema of a path parameter constraint.
+func constraintSchema(constraint string) *Schema {
+	switch constraint {
+	case "":
+		return &Schema{Type: "string"}
+	case "int":
+		return &Schema{Type: "integer"}
+	case "uint":
+		return &Schema{Type: "integer", Minimum: float64Ptr(0)}
+	case "bool":
+		return &Schema{Type: "boolean"}
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "alpha":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"}
+	case "alnum":
+		return &Schema{Type: "string", Pattern: "^[a-zA-Z0-9]+$"}
+	}
+	return &Schema{Type: "string", Pattern: "^(?:" + constraint + ")$"}
+}
@@ -0,0 +1,222 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arthurlch/goryu/app"
+	"github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/openapi"
+	"github.com/arthurlch/goryu/router"
+)
+
+type Address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name" validate:"required,min=2,max=64"`
+	Email     string    `json:"email,omitempty" validate:"omitempty,email"`
+	Role      string    `json:"role" validate:"oneof=admin member"`
+	Tags      []string  `json:"tags" validate:"max=5,dive,alphanum"`
+	Address   *Address  `json:"address"`
+	Friends   []User    `json:"friends"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"-"`
+}
+
+type createUser struct {
+	OrgID  int    `param:"org"`
+	Notify bool   `query:"notify" default:"true"`
+	Trace  string `header:"X-Trace" validate:"required"`
+	Name   string `json:"name" validate:"required"`
+}
+
+func noop(c *context.Context) {}
+
+func generate(t *testing.T, r *router.Router) map[string]interface{} {
+	t.Helper()
+	raw, err := json.Marshal(openapi.Generate(r, openapi.Config{Info: openapi.Info{Title: "Users", Version: "2.0.0"}}))
+	if err != nil {
+		t.Fatalf("failed to encode the document: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("failed to decode the document: %v", err)
+	}
+	return doc
+}
+
+// lookup walks a decoded JSON document along a path of keys.
+func lookup(t *testing.T, v interface{}, keys ...string) interface{} {
+	t.Helper()
+	for _, key := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			t.Fatalf("no object at %q in %v", key, keys)
+		}
+		if v, ok = m[key]; !ok {
+			t.Fatalf("missing %q in %v", key, keys)
+		}
+	}
+	return v
+}
+
+func TestGenerate(t *testing.T) {
+	r := router.New()
+	r.GET("/users/:id<int>", noop).SetName("user.show").
+		SetSummary("Show a user").SetTags("users").
+		SetResponse(http.StatusOK, User{}).SetResponse(http.StatusNotFound, nil)
+	r.POST("/orgs/:org/users", noop).SetTags("users", "orgs").
+		SetRequest((*createUser)(nil)).SetResponse(http.StatusCreated, &User{})
+	r.GET("/files/*path", noop).SetDeprecated()
+	r.GET("/posts/:slug?", noop)
+	r.GET("/internal", noop).SetHidden()
+	r.ALL("/any", noop)
+
+	doc := generate(t, r)
+
+	if doc["openapi"] != "3.1.0" || lookup(t, doc, "info", "title") != "Users" {
+		t.Errorf("unexpected header: %v %v", doc["openapi"], doc["info"])
+	}
+
+	var paths []string
+	for path := range lookup(t, doc, "paths").(map[string]interface{}) {
+		paths = append(paths, path)
+	}
+	want := []string{"/files/{path}", "/orgs/{org}/users", "/posts", "/posts/{slug}", "/users/{id}"}
+	if !reflect.DeepEqual(sortedStrings(paths), want) {
+		t.Errorf("expected paths %v, got %v", want, sortedStrings(paths))
+	}
+
+	show := lookup(t, doc, "paths", "/users/{id}", "get")
+	if lookup(t, show, "operationId") != "user.show" || lookup(t, show, "summary") != "Show a user" {
+		t.Errorf("unexpected operation: %v", show)
+	}
+	if lookup(t, show, "parameters").([]interface{})[0].(map[string]interface{})["schema"].(map[string]interface{})["type"] != "integer" {
+		t.Error("expected the int constraint to give an integer parameter")
+	}
+	if ref := lookup(t, show, "responses", "200", "content", "application/json", "schema", "$ref"); ref != "#/components/schemas/User" {
+		t.Errorf("unexpected response schema: %v", ref)
+	}
+	if _, ok := lookup(t, show, "responses", "404").(map[string]interface{})["content"]; ok {
+		t.Error("expected no content for a nil response type")
+	}
+	if lookup(t, doc, "paths", "/files/{path}", "get", "deprecated") != true {
+		t.Error("expected the deprecated flag")
+	}
+
+	create := lookup(t, doc, "paths", "/orgs/{org}/users", "post")
+	params := lookup(t, create, "parameters").([]interface{})
+	if len(params) != 3 {
+		t.Fatalf("expected path, query and header parameters, got %v", params)
+	}
+	notify := params[1].(map[string]interface{})
+	if notify["in"] != "query" || lookup(t, notify, "schema", "default") != true {
+		t.Errorf("unexpected query parameter: %v", notify)
+	}
+	if trace := params[2].(map[string]interface{}); trace["in"] != "header" || trace["required"] != true {
+		t.Errorf("unexpected header parameter: %v", trace)
+	}
+	body := lookup(t, create, "requestBody", "content", "application/json", "schema")
+	if props := lookup(t, body, "properties").(map[string]interface{}); len(props) != 1 || props["name"] == nil {
+		t.Errorf("expected only the body fields in the request body, got %v", props)
+	}
+
+	user := lookup(t, doc, "components", "schemas", "User")
+	if !reflect.DeepEqual(lookup(t, user, "required"), []interface{}{"name"}) {
+		t.Errorf("unexpected required fields: %v", lookup(t, user, "required"))
+	}
+	name := lookup(t, user, "properties", "name")
+	if lookup(t, name, "minLength") != 2.0 || lookup(t, name, "maxLength") != 64.0 {
+		t.Errorf("expected length bounds from the validate tag, got %v", name)
+	}
+	if lookup(t, user, "properties", "email", "format") != "email" {
+		t.Error("expected the email format")
+	}
+	if !reflect.DeepEqual(lookup(t, user, "properties", "role", "enum"), []interface{}{"admin", "member"}) {
+		t.Error("expected the oneof enum")
+	}
+	tags := lookup(t, user, "properties", "tags")
+	if lookup(t, tags, "maxItems") != 5.0 || lookup(t, tags, "items", "pattern") != "^[a-zA-Z0-9]+$" {
+		t.Errorf("expected dive rules on the items, got %v", tags)
+	}
+	if lookup(t, user, "properties", "friends", "items", "$ref") != "#/components/schemas/User" {
+		t.Error("expected the recursive type to use a reference")
+	}
+	if lookup(t, user, "properties", "created_at", "format") != "date-time" {
+		t.Error("expected time.Time as a date-time string")
+	}
+	if _, ok := lookup(t, user, "properties").(map[string]interface{})["Secret"]; ok {
+		t.Error("expected fields tagged json:\"-\" to be left out")
+	}
+	if lookup(t, doc, "components", "schemas", "Address", "type") != "object" {
+		t.Error("expected the nested struct as a component")
+	}
+}
+
+func TestApp_OpenAPI(t *testing.T) {
+	api := app.New()
+	api.GET("/users/:id", noop).SetTags("users")
+	api.OpenAPI(openapi.Config{Info: openapi.Info{Title: "Users <API>"}})
+
+	root := app.New()
+	root.GET("/health", noop)
+	root.Mount("/api", api)
+	root.OpenAPI(openapi.Config{UIPath: "-"})
+
+	rr := httptest.NewRecorder()
+	root.ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+	var doc openapi.Document
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode the document: %v", err)
+	}
+	if doc.Paths["/health"] == nil || doc.Paths["/api/users/{id}"] == nil {
+		t.Errorf("expected the root and mounted routes, got %v", doc.Paths)
+	}
+	if doc.Paths["/openapi.json"] != nil || doc.Paths["/api/docs"] != nil {
+		t.Error("expected the documentation routes to be hidden")
+	}
+
+	rr = httptest.NewRecorder()
+	root.ServeHTTP(rr, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	doc = openapi.Document{}
+	if err := json.NewDecoder(rr.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode the mounted document: %v", err)
+	}
+	if doc.Paths["/users/{id}"] == nil || len(doc.Servers) != 1 || doc.Servers[0].URL != "/api" {
+		t.Errorf("expected mounted paths relative to the /api server, got %v %v", doc.Paths, doc.Servers)
+	}
+
+	rr = httptest.NewRecorder()
+	root.ServeHTTP(rr, httptest.NewRequest("GET", "/api/docs", nil))
+	page := rr.Body.String()
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("expected the viewer page, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(page, `"/api/openapi.json"`) || !strings.Contains(page, "Users &lt;API&gt;") {
+		t.Error("expected the escaped title and the mounted document URL in the page")
+	}
+	if strings.Contains(page, "<script src=") || strings.Contains(page, "<link") {
+		t.Error("expected a page without external assets")
+	}
+
+	rr = httptest.NewRecorder()
+	root.ServeHTTP(rr, httptest.NewRequest("GET", "/docs", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected the disabled viewer to be missing, got %d", rr.Code)
+	}
+}
+
+func sortedStrings(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,398 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12), as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	componentNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// valueTags are the tags of the binders reading request values instead of
+// the body, with the OpenAPI location of their parameters.
+var valueTags = []struct{ tag, in string }{
+	{"param", "path"},
+	{"query", "query"},
+	{"header", "header"},
+	{"cookie", "cookie"},
+}
+
+// schemas reflects Go types into schemas, registering named structs as
+// components.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the schema of t. Named structs are referenced from the
+// components.
+func (s *schemas) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float64Ptr(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	// Interfaces and other kinds accept any value.
+	return &Schema{}
+}
+
+// component registers the named struct t and returns its component name.
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := componentNameRegexp.ReplaceAllString(t.Name(), "_")
+	if _, taken := s.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = componentNameRegexp.ReplaceAllString(pkg+"."+t.Name(), "_")
+		for i := 2; s.components[name] != nil; i++ {
+			name = componentNameRegexp.ReplaceAllString(pkg+"."+t.Name(), "_") + strconv.Itoa(i)
+		}
+	}
+	// Registered before the fields are reflected so recursive types end
+	// with a reference.
+	s.names[t] = name
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of the JSON fields of t.
+func (s *schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(schema, t, nil)
+	return schema
+}
+
+// addFields adds the JSON fields of t accepted by include to schema,
+// flattening embedded structs like encoding/json.
+func (s *schemas) addFields(schema *Schema, t reflect.Type, include func(reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addFields(schema, ft, include)
+				continue
+			}
+		}
+		if !field.IsExported() || (include != nil && !include(field)) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldSchema, required := s.fieldSchema(field)
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// fieldSchema returns the schema of a struct field, with the constraints
+// of its `validate` and `default` tags, and whether it is required.
+func (s *schemas) fieldSchema(field reflect.StructField) (*Schema, bool) {
+	schema := s.schema(field.Type)
+	if def, ok := field.Tag.Lookup("default"); ok {
+		schema.Default = typedValue(schema, def)
+	}
+	required := false
+	target := schema
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "required":
+			if target == schema {
+				required = true
+			}
+		case "dive":
+			switch {
+			case target.Items != nil:
+				target = target.Items
+			case target.AdditionalProperties != nil:
+				target = target.AdditionalProperties
+			default:
+				return schema, required
+			}
+		default:
+			applyRule(target, name, param)
+		}
+	}
+	return schema, required
+}
+
+// applyRule adds the JSON Schema equivalent of a validation rule.
+func applyRule(schema *Schema, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	bound := err == nil
+	switch rule {
+	case "min", "gte":
+		if bound {
+			setLowerBound(schema, n, false)
+		}
+	case "gt":
+		if bound {
+			setLowerBound(schema, n, true)
+		}
+	case "max", "lte":
+		if bound {
+			setUpperBound(schema, n, false)
+		}
+	case "lt":
+		if bound {
+			setUpperBound(schema, n, true)
+		}
+	case "len":
+		if bound {
+			setLowerBound(schema, n, false)
+			setUpperBound(schema, n, false)
+		}
+	case "oneof":
+		for _, option := range strings.Fields(param) {
+			schema.Enum = append(schema.Enum, typedValue(schema, option))
+		}
+	case "email":
+		schema.Format = "email"
+	case "url":
+		schema.Format = "uri"
+	case "uuid":
+		schema.Format = "uuid"
+	case "alpha":
+		schema.Pattern = "^[a-zA-Z]+$"
+	case "alphanum":
+		schema.Pattern = "^[a-zA-Z0-9]+$"
+	case "numeric":
+		schema.Pattern = `^[-+]?[0-9]+(\.[0-9]+)?$`
+	}
+}
+
+func setLowerBound(schema *Schema, n float64, exclusive bool) {
+	if exclusive && (schema.Type == "string" || schema.Type == "array") {
+		n++
+	}
+	switch schema.Type {
+	case "string":
+		schema.MinLength = intPtr(int(n))
+	case "array":
+		schema.MinItems = intPtr(int(n))
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMinimum = float64Ptr(n)
+		} else {
+			schema.Minimum = float64Ptr(n)
+		}
+	}
+}
+
+func setUpperBound(schema *Schema, n float64, exclusive bool) {
+	if exclusive && (schema.Type == "string" || schema.Type == "array") {
+		n--
+	}
+	switch schema.Type {
+	case "string":
+		schema.MaxLength = intPtr(int(n))
+	case "array":
+		schema.MaxItems = intPtr(int(n))
+	case "integer", "number":
+		if exclusive {
+			schema.ExclusiveMaximum = float64Ptr(n)
+		} else {
+			schema.Maximum = float64Ptr(n)
+		}
+	}
+}
+
+// typedValue converts a tag value to the type of schema.
+func typedValue(schema *Schema, value string) interface{} {
+	switch schema.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// requestFields splits a request type into parameters and body, the way
+// the context binders read it.
+type requestFields struct {
+	// params, order and required are keyed by parameter location.
+	params   map[string]map[string]*Schema
+	order    map[string][]string
+	required map[string]map[string]bool
+	body     *Schema
+}
+
+func (s *schemas) requestFields(t reflect.Type) requestFields {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := requestFields{
+		params:   make(map[string]map[string]*Schema),
+		order:    make(map[string][]string),
+		required: make(map[string]map[string]bool),
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		fields.body = s.schema(t)
+		return fields
+	}
+
+	s.addParams(&fields, t)
+	isBody := func(field reflect.StructField) bool {
+		if _, ok := field.Tag.Lookup("json"); ok {
+			return true
+		}
+		for _, vt := range valueTags {
+			if _, ok := field.Tag.Lookup(vt.tag); ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	body := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(body, t, isBody)
+	switch {
+	case len(body.Properties) == 0:
+	case len(fields.order) == 0 && len(fields.params) == 0 && t.Name() != "":
+		fields.body = s.schema(t)
+	default:
+		fields.body = body
+	}
+	return fields
+}
+
+// addParams collects the fields of t tagged for a parameter location.
+func (s *schemas) addParams(fields *requestFields, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				s.addParams(fields, ft)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		for _, vt := range valueTags {
+			name, _, _ := strings.Cut(field.Tag.Get(vt.tag), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			schema, required := s.fieldSchema(field)
+			if fields.params[vt.in] == nil {
+				fields.params[vt.in] = make(map[string]*Schema)
+				fields.required[vt.in] = make(map[string]bool)
+			}
+			if _, seen := fields.params[vt.in][name]; !seen {
+				fields.order[vt.in] = append(fields.order[vt.in], name)
+			}
+			fields.params[vt.in][name] = schema
+			fields.required[vt.in][name] = required
+		}
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,24 @@
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+)
+
+// viewerHTML renders a document with no external assets, so it works
+// offline.
+//
+//go:embed viewer.html
+var viewerHTML string
+
+var viewerTemplate = template.Must(template.New("viewer").Parse(viewerHTML))
+
+// Viewer returns the HTML page rendering the document served at specURL.
+func Viewer(title, specURL string) []byte {
+	var buf bytes.Buffer
+	if err := viewerTemplate.Execute(&buf, struct{ Title, SpecURL string }{title, specURL}); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
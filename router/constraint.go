@@ -0,0 +1,140 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// constraints are the named checks usable as ":name<constraint>". Any other
+// constraint is compiled as a regular expression matching the whole segment.
+var constraints = map[string]func(string) bool{
+	"int":   isInt,
+	"uint":  isUint,
+	"alpha": isAlpha,
+	"alnum": isAlnum,
+	"uuid":  isUUID,
+	"bool": func(s string) bool {
+		return s == "true" || s == "false"
+	},
+}
+
+// paramSegment is a parsed ":name<constraint>?" pattern segment.
+type paramSegment struct {
+	name       string
+	expr       string
+	optional   bool
+	constraint func(string) bool
+}
+
+func parseParam(part string) (paramSegment, error) {
+	seg := paramSegment{}
+	part = strings.TrimPrefix(part, ":")
+
+	if strings.HasSuffix(part, "?") {
+		seg.optional = true
+		part = part[:len(part)-1]
+	}
+
+	if open := strings.IndexByte(part, '<'); open >= 0 {
+		if !strings.HasSuffix(part, ">") {
+			return seg, fmt.Errorf("unterminated constraint in %q", part)
+		}
+		seg.expr = part[open+1 : len(part)-1]
+		part = part[:open]
+		if seg.expr == "" {
+			return seg, fmt.Errorf("empty constraint for parameter %q", part)
+		}
+
+		if check, ok := constraints[seg.expr]; ok {
+			seg.constraint = check
+		} else {
+			re, err := regexp.Compile("^(?:" + seg.expr + ")$")
+			if err != nil {
+				return seg, fmt.Errorf("invalid constraint for parameter %q: %v", part, err)
+			}
+			seg.constraint = re.MatchString
+		}
+	}
+
+	seg.name = part
+	if seg.name == "" {
+		return seg, fmt.Errorf("unnamed parameter")
+	}
+	return seg, nil
+}
+
+// paramName returns the name of a ":param" or "*catchall" pattern segment.
+func paramName(part string) string {
+	if segmentKind(part) == catchAllNode {
+		return part[1:]
+	}
+	seg, _ := parseParam(part)
+	return seg.name
+}
+
+func isInt(s string) bool {
+	return isUint(strings.TrimPrefix(s, "-"))
+}
+
+func isUint(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i] | 0x20
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlnum(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isAlpha(s[i:i+1]) && !isUint(s[i:i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if '0' <= c && c <= '9' {
+				continue
+			}
+			// Folding the case is only safe for letters: it also maps
+			// control bytes such as 0x10 to digits.
+			if c |= 0x20; c < 'a' || c > 'f' {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package router
+
+import "reflect"
+
+// RouteDoc describes a route for API documentation such as OpenAPI.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// Hidden leaves the route out of the documentation.
+	Hidden bool
+	// Request is the type the request is bound into.
+	Request reflect.Type
+	// Responses maps status codes to the type of their body, nil for an
+	// empty body.
+	Responses map[int]reflect.Type
+}
+
+// SetSummary sets the one-line summary of the route.
+func (r *Route) SetSummary(summary string) *Route {
+	r.Doc.Summary = summary
+	return r
+}
+
+// SetDescription sets the longer description of the route.
+func (r *Route) SetDescription(description string) *Route {
+	r.Doc.Description = description
+	return r
+}
+
+// SetTags sets the tags grouping the route in the documentation.
+func (r *Route) SetTags(tags ...string) *Route {
+	r.Doc.Tags = tags
+	return r
+}
+
+// SetDeprecated marks the route as deprecated.
+func (r *Route) SetDeprecated() *Route {
+	r.Doc.Deprecated = true
+	return r
+}
+
+// SetHidden leaves the route out of the documentation.
+func (r *Route) SetHidden() *Route {
+	r.Doc.Hidden = true
+	return r
+}
+
+// SetRequest documents the request type, given as a value or a nil pointer
+// of that type, e.g. (*CreateUser)(nil).
+func (r *Route) SetRequest(v interface{}) *Route {
+	r.Doc.Request = docType(v)
+	return r
+}
+
+// SetResponse documents the response sent with code, given as a value or a
+// nil pointer of its type. A nil v documents an empty body.
+func (r *Route) SetResponse(code int, v interface{}) *Route {
+	if r.Doc.Responses == nil {
+		r.Doc.Responses = make(map[int]reflect.Type)
+	}
+	r.Doc.Responses[code] = docType(v)
+	return r
+}
+
+func docType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Segment is one segment of a route pattern.
+type Segment struct {
+	// Static is the literal text of a static segment.
+	Static string
+	// Param is the name of a parameter or catch-all segment.
+	Param string
+	// Constraint is a named constraint such as "int" or a regular
+	// expression.
+	Constraint string
+	Optional   bool
+	CatchAll   bool
+}
+
+// Segments returns the parsed segments of the route's path.
+func (r *Route) Segments() []Segment {
+	strict := r.router != nil && r.router.StrictRouting
+	parts := splitPattern(r.Path, strict)
+	segments := make([]Segment, 0, len(parts))
+	for _, part := range parts {
+		switch segmentKind(part) {
+		case paramNode:
+			seg, _ := parseParam(part)
+			segments = append(segments, Segment{Param: seg.name, Constraint: seg.expr, Optional: seg.optional})
+		case catchAllNode:
+			segments = append(segments, Segment{Param: part[1:], CatchAll: true})
+		default:
+			segments = append(segments, Segment{Static: part})
+		}
+	}
+	return segments
+}
+
+// Mounts returns the routers mounted under this one.
+func (router *Router) Mounts() []*Router {
+	router.namesMu.RLock()
+	defer router.namesMu.RUnlock()
+	return append([]*Router(nil), router.mounted...)
+}
@@ -0,0 +1,133 @@
+package router
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/arthurlch/goryu/context"
+)
+
+// Group registers routes under a common prefix and middleware.
+type Group struct {
+	prefix string
+	// parent is the group this one is nested in, whose middleware runs
+	// first. middlewares are the group's own, guarded by the router's mu.
+	parent      *Group
+	middlewares context.HandlersChain
+	router      *Router
+}
+
+// Group creates a nested group. It runs the middleware of g, including the
+// middleware added to g later, before its own.
+func (g *Group) Group(prefix string, middlewares ...context.Middleware) *Group {
+	return g.router.newGroup(&Group{
+		prefix:      g.prefix + prefix,
+		parent:      g,
+		router:      g.router,
+		middlewares: adaptMiddlewares(middlewares),
+	})
+}
+
+// Route creates a nested group and passes it to fn, to keep the routes of a
+// prefix together.
+func (g *Group) Route(prefix string, fn func(g *Group), middlewares ...context.Middleware) *Group {
+	group := g.Group(prefix, middlewares...)
+	fn(group)
+	return group
+}
+
+// Use adds middleware to the group and its nested groups, around the
+// routes registered before or after the call. Like Router.Use, it should be
+// called before the router starts serving.
+func (g *Group) Use(middlewares ...context.Middleware) {
+	g.UseHandler(adaptMiddlewares(middlewares)...)
+}
+
+// UseHandler is like Use for chain handlers, which continue the chain by
+// calling Context.Next.
+func (g *Group) UseHandler(handlers ...context.HandlerFunc) {
+	router := g.router
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	g.middlewares = append(g.middlewares, handlers...)
+	if router.built.Load() {
+		log.Printf("[GORYU] warning: Group.Use called after the router started serving; in-flight requests may not see the new middleware")
+		for _, route := range router.routes {
+			route.compose(router.middlewares)
+		}
+	}
+}
+
+// chain returns the middleware of the group, after that of its parents.
+// The router's mu must be held.
+func (g *Group) chain() context.HandlersChain {
+	if g == nil {
+		return nil
+	}
+	return combineHandlers(g.parent.chain(), g.middlewares...)
+}
+
+// Prefix returns the path prefix of the group.
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+func (g *Group) Add(method, path string, handler context.HandlerFunc) *Route {
+	return g.router.add(method, g.prefix+path, g, context.HandlersChain{handler})
+}
+
+func (g *Group) GET(path string, handler context.HandlerFunc) *Route {
+	return g.Add("GET", path, handler)
+}
+
+func (g *Group) POST(path string, handler context.HandlerFunc) *Route {
+	return g.Add("POST", path, handler)
+}
+
+func (g *Group) PUT(path string, handler context.HandlerFunc) *Route {
+	return g.Add("PUT", path, handler)
+}
+
+func (g *Group) DELETE(path string, handler context.HandlerFunc) *Route {
+	return g.Add("DELETE", path, handler)
+}
+
+func (g *Group) PATCH(path string, handler context.HandlerFunc) *Route {
+	return g.Add("PATCH", path, handler)
+}
+
+func (g *Group) HEAD(path string, handler context.HandlerFunc) *Route {
+	return g.Add("HEAD", path, handler)
+}
+
+func (g *Group) OPTIONS(path string, handler context.HandlerFunc) *Route {
+	return g.Add("OPTIONS", path, handler)
+}
+
+func (g *Group) ALL(path string, handler context.HandlerFunc) *Route {
+	return g.Add("ALL", path, handler)
+}
+
+// Mount forwards every request under the group prefix plus prefix to
+// handler, through the group's middleware.
+func (g *Group) Mount(prefix string, handler http.Handler) *Route {
+	return g.router.mount(g.prefix+prefix, handler, g)
+}
+
+// Static serves the files under root at the group prefix plus prefix.
+func (g *Group) Static(prefix, root string) *Route {
+	return g.router.static(g.prefix+prefix, root, g)
+}
+
+// NotFound sets the handler for unmatched requests under the group prefix.
+// It is wrapped with the group's middleware.
+func (g *Group) NotFound(handler context.HandlerFunc) {
+	g.router.setFallback(g.prefix, &groupHandler{group: g, handler: handler}, nil)
+}
+
+// MethodNotAllowed sets the 405 handler for requests under the group prefix.
+// It is wrapped with the group's middleware.
+func (g *Group) MethodNotAllowed(handler context.HandlerFunc) {
+	g.router.setFallback(g.prefix, nil, &groupHandler{group: g, handler: handler})
+}
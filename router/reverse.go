@@ -0,0 +1,166 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (router *Router) nameRoute(route *Route, name string) {
+	router.namesMu.Lock()
+	defer router.namesMu.Unlock()
+
+	if existing, ok := router.namedRoutes[name]; ok && existing != route {
+		panic(fmt.Sprintf("router: route name %q is already used by %s %s", name, existing.Method, existing.Path))
+	}
+	if route.Name != "" && router.namedRoutes[route.Name] == route {
+		delete(router.namedRoutes, route.Name)
+	}
+	router.namedRoutes[name] = route
+}
+
+// Mountable is implemented by handlers that need to know where Mount
+// attached them, such as Router and app.App.
+type Mountable interface {
+	http.Handler
+	// Mounted is called with the parent router and the prefix, relative to
+	// that router, the handler is mounted under.
+	Mounted(parent *Router, prefix string)
+}
+
+// Mounted records that the router is mounted under prefix, so its Reverse
+// includes the prefix and the parent's Reverse resolves its route names.
+func (router *Router) Mounted(parent *Router, prefix string) {
+	router.parent = parent
+	router.mountPrefix = strings.TrimSuffix(prefix, "/")
+	if router.Proxy == nil {
+		router.Proxy = parent.Proxy
+	}
+	parent.namesMu.Lock()
+	parent.mounted = append(parent.mounted, router)
+	parent.namesMu.Unlock()
+}
+
+// Prefix returns the path under which the router is mounted, or "".
+func (router *Router) Prefix() string {
+	if router.parent == nil {
+		return router.mountPrefix
+	}
+	return router.parent.Prefix() + router.mountPrefix
+}
+
+// Route returns the route registered under name, looking into mounted
+// routers as well.
+func (router *Router) Route(name string) (*Route, bool) {
+	router.namesMu.RLock()
+	route, ok := router.namedRoutes[name]
+	mounted := router.mounted
+	router.namesMu.RUnlock()
+	if ok {
+		return route, true
+	}
+	for _, sub := range mounted {
+		if route, ok := sub.Route(name); ok {
+			return route, true
+		}
+	}
+	return nil, false
+}
+
+// Reverse builds the URL path of a named route, including any mount prefix.
+// Arguments fill the route parameters:
+//
+//   - plain values are used in order for the parameters not set by name;
+//   - a map[string]string or map[string]interface{} sets parameters by name,
+//     and its keys that are not route parameters become query arguments;
+//   - url.Values are appended as query arguments.
+//
+// Values are URL-escaped; a catch-all keeps its slashes. Reverse returns ""
+// when the route is unknown, a required parameter is missing or a value does
+// not satisfy the parameter's constraint.
+func (router *Router) Reverse(name string, params ...interface{}) string {
+	route, ok := router.Route(name)
+	if !ok {
+		return ""
+	}
+
+	var positional []interface{}
+	named := make(map[string]string)
+	query := url.Values{}
+	for _, param := range params {
+		switch v := param.(type) {
+		case url.Values:
+			for key, values := range v {
+				query[key] = append(query[key], values...)
+			}
+		case map[string]string:
+			for key, value := range v {
+				named[key] = value
+			}
+		case map[string]interface{}:
+			for key, value := range v {
+				named[key] = fmt.Sprint(value)
+			}
+		default:
+			positional = append(positional, v)
+		}
+	}
+
+	next := func(name string) (string, bool) {
+		if value, ok := named[name]; ok {
+			delete(named, name)
+			return value, true
+		}
+		if len(positional) == 0 {
+			return "", false
+		}
+		value := fmt.Sprint(positional[0])
+		positional = positional[1:]
+		return value, true
+	}
+
+	var b strings.Builder
+	b.WriteString(route.router.Prefix())
+	for _, part := range splitPattern(route.Path, route.router.StrictRouting) {
+		switch segmentKind(part) {
+		case paramNode:
+			seg, _ := parseParam(part)
+			value, ok := next(seg.name)
+			if !ok {
+				if seg.optional {
+					continue
+				}
+				return ""
+			}
+			if value == "" || (seg.constraint != nil && !seg.constraint(value)) {
+				return ""
+			}
+			b.WriteByte('/')
+			b.WriteString(url.PathEscape(value))
+		case catchAllNode:
+			value, _ := next(part[1:])
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			b.WriteByte('/')
+			b.WriteString(strings.Join(segments, "/"))
+		default:
+			b.WriteByte('/')
+			b.WriteString(part)
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteByte('/')
+	}
+
+	for key, value := range named {
+		query.Add(key, value)
+	}
+	if len(query) > 0 {
+		b.WriteByte('?')
+		b.WriteString(query.Encode())
+	}
+	return b.String()
+}
@@ -2,8 +2,13 @@ package router
 
 import (
 	"fmt"
+	"log"
 	"net/http"
+	pathpkg "path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/arthurlch/goryu/context"
 )
@@ -13,41 +18,243 @@ type Route struct {
 	Path    string
 	Handler context.HandlerFunc
 	Name    string
+	// Doc describes the route for API documentation.
+	Doc RouteDoc
+
+	router *Router
+	// group is the Group the route was registered on, if any. chain is the
+	// router middleware, then the group middleware, then handlers, which
+	// ends with Handler.
+	group    *Group
+	handlers context.HandlersChain
+	chain    atomic.Pointer[context.HandlersChain]
+}
+
+// compose builds the chain of the route. The router's mu must be held.
+func (r *Route) compose(middlewares context.HandlersChain) {
+	chain := combineHandlers(combineHandlers(middlewares, r.group.chain()...), r.handlers...)
+	r.chain.Store(&chain)
 }
 
+// SetName names the route so its path can be built with Router.Reverse. It
+// panics if another route already uses the name.
 func (r *Route) SetName(name string) *Route {
+	if r.router != nil {
+		r.router.nameRoute(r, name)
+	}
 	r.Name = name
 	return r
 }
 
-type Group struct {
-	prefix      string
-	middlewares []context.Middleware
-	router      *Router
-}
-
 type Router struct {
 	trees       map[string]*node
 	namedRoutes map[string]*Route
+	namesMu     sync.RWMutex
+
+	// parent and mountPrefix are set when the router is mounted under
+	// another one, so reversed paths include the mount prefix.
+	parent      *Router
+	mountPrefix string
+	mounted     []*Router
+
+	// ErrorHandler renders errors passed to Context.Error.
+	// Default: context.DefaultErrorHandler
+	ErrorHandler context.ErrorHandler
+	// Proxy lists the trusted proxies whose forwarding headers the Context
+	// reads. A mounted router without one uses its parent's.
+	// Default: nil, forwarding headers are ignored
+	Proxy *context.ProxyConfig
+
+	// HandleMethodNotAllowed answers 405 with an Allow header when the path
+	// is registered under other methods only.
+	// Default: true
+	HandleMethodNotAllowed bool
+	// HandleOPTIONS answers OPTIONS requests with the allowed methods when
+	// no OPTIONS handler is registered for the path.
+	// Default: true
+	HandleOPTIONS bool
+	// HandleHEAD serves HEAD requests from the GET handler, discarding the
+	// body, when no HEAD handler is registered for the path.
+	// Default: true
+	HandleHEAD bool
+
+	// StrictRouting treats "/users" and "/users/" as different routes.
+	// It must be set before routes are registered.
+	// Default: false
+	StrictRouting bool
+	// CaseSensitive makes "/Users" and "/users" different routes.
+	// Default: false
+	CaseSensitive bool
+	// RedirectTrailingSlash redirects to the path with or without the
+	// trailing slash when only the other one is registered. It only has an
+	// effect with StrictRouting.
+	// Default: false
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects to the registered path after removing
+	// "..", duplicate slashes and case differences from the request path.
+	// Default: false
+	RedirectFixedPath bool
+
+	// NotFound handles requests that match no route.
+	// Default: responds 404 through Context.Error
+	NotFound context.HandlerFunc
+	// MethodNotAllowed handles requests whose path is only registered under
+	// other methods. The Allow header is already set when it runs.
+	// Default: responds 405 through Context.Error
+	MethodNotAllowed context.HandlerFunc
+	fallbacks        []*fallback
+
+	// OnRoute is called after each route is registered, including the
+	// routes added by Mount and Static.
+	// Default: nil
+	OnRoute func(*Route)
+	// OnGroup is called after each group is created.
+	// Default: nil
+	OnGroup func(*Group)
+
+	pool      sync.Pool
+	maxParams int
+
+	// mu guards middlewares and routes. The middleware is composed around
+	// every route when the router starts serving.
+	mu          sync.Mutex
+	middlewares context.HandlersChain
+	routes      []*Route
+	built       atomic.Bool
+}
+
+// fallback holds the NotFound and MethodNotAllowed handlers of a Group, used
+// for unmatched requests under its prefix.
+type fallback struct {
+	prefix           string
+	notFound         *groupHandler
+	methodNotAllowed *groupHandler
+}
+
+// groupHandler is a handler run after the middleware of its group.
+type groupHandler struct {
+	group   *Group
+	handler context.HandlerFunc
 }
 
 func New() *Router {
-	return &Router{
-		trees:       make(map[string]*node),
-		namedRoutes: make(map[string]*Route),
+	router := &Router{
+		trees:                  make(map[string]*node),
+		namedRoutes:            make(map[string]*Route),
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+		HandleHEAD:             true,
 	}
+	router.pool.New = func() interface{} {
+		ctx := context.NewContext(nil, nil)
+		ctx.Params = make(context.Params, 0, router.maxParams)
+		return ctx
+	}
+	return router
 }
 
 func (router *Router) Add(method, path string, handler context.HandlerFunc) *Route {
+	return router.add(method, path, nil, context.HandlersChain{handler})
+}
+
+// add registers a route of group, which may be nil, running handlers after
+// the group middleware. The last handler is the route handler.
+func (router *Router) add(method, path string, group *Group, handlers context.HandlersChain) *Route {
 	if _, ok := router.trees[method]; !ok {
 		router.trees[method] = &node{}
 	}
-	parts := parsePath(path)
-	route := &Route{Method: method, Path: path, Handler: handler}
-	router.trees[method].insert(path, parts, 0, route)
+	parts := splitPattern(path, router.StrictRouting)
+	route := &Route{
+		Method:   method,
+		Path:     path,
+		Handler:  handlers[len(handlers)-1],
+		router:   router,
+		group:    group,
+		handlers: handlers,
+	}
+	router.trees[method].insert(path, parts, 0, route, router.matchOptions())
+
+	// An optional last parameter also registers the path without it.
+	for i, part := range parts {
+		if segmentKind(part) != paramNode || !strings.HasSuffix(part, "?") {
+			continue
+		}
+		if i != len(parts)-1 {
+			panic(fmt.Sprintf("router: optional parameter %q must be the last segment in %s", part, path))
+		}
+		short := parts[:i]
+		if len(short) == 0 {
+			short = []string{""}
+		}
+		router.trees[method].insert(path, short, 0, route, router.matchOptions())
+	}
+
+	params := 0
+	for _, part := range parts {
+		if kind := segmentKind(part); kind == paramNode || kind == catchAllNode {
+			params++
+		}
+	}
+	if params > router.maxParams {
+		router.maxParams = params
+	}
+
+	router.mu.Lock()
+	router.routes = append(router.routes, route)
+	if router.built.Load() {
+		route.compose(router.middlewares)
+	}
+	router.mu.Unlock()
+
+	if router.OnRoute != nil {
+		router.OnRoute(route)
+	}
 	return route
 }
 
+// Use adds middleware around every route, whenever it was registered, and
+// around the NotFound and MethodNotAllowed handlers. It should be called
+// before the router starts serving.
+func (router *Router) Use(middlewares ...context.Middleware) {
+	router.UseHandler(adaptMiddlewares(middlewares)...)
+}
+
+// UseHandler is like Use for chain handlers, which continue the chain by
+// calling Context.Next.
+func (router *Router) UseHandler(handlers ...context.HandlerFunc) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	router.middlewares = append(router.middlewares, handlers...)
+	if router.built.Load() {
+		log.Printf("[GORYU] warning: Use called after the router started serving; in-flight requests may not see the new middleware")
+		for _, route := range router.routes {
+			route.compose(router.middlewares)
+		}
+	}
+}
+
+// Routes returns the registered routes in registration order.
+func (router *Router) Routes() []*Route {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	return append([]*Route(nil), router.routes...)
+}
+
+// build composes the middleware around every registered route. It runs on
+// the first request; routes added later are composed when registered.
+func (router *Router) build() {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	if router.built.Load() {
+		return
+	}
+	for _, route := range router.routes {
+		route.compose(router.middlewares)
+	}
+	router.built.Store(true)
+}
+
 func (router *Router) GET(path string, handler context.HandlerFunc) *Route {
 	return router.Add("GET", path, handler)
 }
@@ -81,97 +288,387 @@ func (router *Router) ALL(path string, handler context.HandlerFunc) *Route {
 }
 
 func (router *Router) Group(prefix string, middlewares ...context.Middleware) *Group {
-	return &Group{
+	return router.newGroup(&Group{
 		prefix:      prefix,
 		router:      router,
-		middlewares: middlewares,
+		middlewares: adaptMiddlewares(middlewares),
+	})
+}
+
+func (router *Router) newGroup(group *Group) *Group {
+	if router.OnGroup != nil {
+		router.OnGroup(group)
 	}
+	return group
 }
 
-func (router *Router) Reverse(name string, params ...interface{}) string {
-	route, ok := router.namedRoutes[name]
-	if !ok {
-		return ""
+// Mount forwards every request under prefix to handler with the prefix
+// stripped from the URL path. A Mountable handler is told where it was
+// mounted.
+func (router *Router) Mount(prefix string, handler http.Handler) *Route {
+	return router.mount(prefix, handler, nil)
+}
+
+func (router *Router) mount(prefix string, handler http.Handler, group *Group) *Route {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if m, ok := handler.(Mountable); ok {
+		m.Mounted(router, prefix)
 	}
 
-	path := route.Path
-	paramIndex := 0
-	for _, part := range parsePath(path) {
-		if strings.HasPrefix(part, ":") && paramIndex < len(params) {
-			path = strings.Replace(path, part, fmt.Sprint(params[paramIndex]), 1)
-			paramIndex++
-		}
+	forward := func(c *context.Context) {
+		originalPath, originalRawPath := c.Request.URL.Path, c.Request.URL.RawPath
+		c.Request.URL.Path = "/" + c.Param("subpath")
+		c.Request.URL.RawPath = ""
+
+		handler.ServeHTTP(c.Writer, c.Request)
+
+		c.Request.URL.Path, c.Request.URL.RawPath = originalPath, originalRawPath
 	}
-	return path
+	return router.add("ALL", prefix+"/*subpath", group, context.HandlersChain{forward})
 }
 
+// Static serves the files under root at prefix.
+func (router *Router) Static(prefix, root string) *Route {
+	return router.static(prefix, root, nil)
+}
+
+func (router *Router) static(prefix, root string, group *Group) *Route {
+	fs := http.FileServer(http.Dir(root))
+
+	handler := func(c *context.Context) {
+		req := new(http.Request)
+		*req = *c.Request
+		u := *c.Request.URL
+		u.Path = "/" + c.Param("filepath")
+		u.RawPath = ""
+		req.URL = &u
+		fs.ServeHTTP(c.Writer, req)
+	}
+
+	routePath := prefix
+	if !strings.HasSuffix(routePath, "/") {
+		routePath += "/"
+	}
+	routePath += "*filepath"
+
+	return router.add("GET", routePath, group, context.HandlersChain{handler})
+}
+
+// ServeHTTP dispatches the request to the matching route. Contexts are
+// pooled, so handlers must not keep a reference to one after returning.
 func (router *Router) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	ctx := context.NewContext(writer, request)
+	if !router.built.Load() {
+		router.build()
+	}
+
+	ctx := router.pool.Get().(*context.Context)
+	ctx.Reset(writer, request)
+	ctx.SetErrorHandler(router.ErrorHandler)
+	ctx.SetURLBuilder(router)
+	ctx.SetProxyConfig(router.Proxy)
+	router.handle(ctx)
+	router.pool.Put(ctx)
+}
 
-	tree, ok := router.trees[request.Method]
-	var node *node
-	var params map[string]string
+func (router *Router) handle(ctx *context.Context) {
+	writer, request := ctx.Writer, ctx.Request
+	path := request.URL.Path
+	node := router.find(request.Method, path, &ctx.Params)
 
-	if ok {
-		parts := parsePath(request.URL.Path)
-		node, params = tree.find(parts, 0)
+	if node == nil && request.Method == http.MethodHead && router.HandleHEAD {
+		node = router.find(http.MethodGet, path, &ctx.Params)
+		if node != nil {
+			ctx.Writer = &headResponseWriter{ResponseWriter: writer}
+		}
 	}
 
 	if node == nil {
-		tree, ok = router.trees["ALL"]
-		if ok {
-			parts := parsePath(request.URL.Path)
-			node, params = tree.find(parts, 0)
+		node = router.find("ALL", path, &ctx.Params)
+	}
+
+	if node == nil && request.Method != http.MethodConnect {
+		if location, ok := router.redirectPath(request.Method, path); ok {
+			code := http.StatusMovedPermanently
+			if request.Method != http.MethodGet && request.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+			if request.URL.RawQuery != "" {
+				location += "?" + request.URL.RawQuery
+			}
+			http.Redirect(writer, request, location, code)
+			return
 		}
 	}
 
-	// If still no handler, return 404 ...
 	if node == nil {
-		http.NotFound(writer, request)
+		if request.Method == http.MethodOptions && router.HandleOPTIONS {
+			if allow := router.allowed(path); allow != "" {
+				writer.Header().Set("Allow", allow)
+				ctx.SetHandlers(router.withMiddlewares(optionsHandler))
+				ctx.Next()
+				return
+			}
+		}
+		if router.HandleMethodNotAllowed {
+			if allow := router.allowed(path); allow != "" {
+				writer.Header().Set("Allow", allow)
+				ctx.SetHandlers(router.fallbackHandlers(path, true))
+				ctx.Next()
+				return
+			}
+		}
+	}
+
+	if node == nil {
+		ctx.SetHandlers(router.fallbackHandlers(path, false))
+		ctx.Next()
 		return
 	}
 
-	ctx.Params = params
-	if node.route.Name != "" {
-		router.namedRoutes[node.route.Name] = node.route
+	ctx.SetHandlers(*node.route.chain.Load())
+	ctx.Next()
+}
+
+// fallbackHandlers returns the chain for the NotFound or MethodNotAllowed
+// handler of path, preferring the Group with the longest matching prefix.
+func (router *Router) fallbackHandlers(path string, methodNotAllowed bool) context.HandlersChain {
+	var handler context.HandlerFunc
+	var group *Group
+	matched := -1
+	for _, fb := range router.fallbacks {
+		gh := fb.notFound
+		if methodNotAllowed {
+			gh = fb.methodNotAllowed
+		}
+		if gh == nil || len(fb.prefix) <= matched || !hasPathPrefix(path, fb.prefix) {
+			continue
+		}
+		handler, group, matched = gh.handler, gh.group, len(fb.prefix)
+	}
+
+	if handler == nil {
+		handler = router.NotFound
+		if methodNotAllowed {
+			handler = router.MethodNotAllowed
+		}
+		if handler == nil {
+			code := http.StatusNotFound
+			if methodNotAllowed {
+				code = http.StatusMethodNotAllowed
+			}
+			handler = func(c *context.Context) {
+				c.Error(context.NewHTTPError(code))
+			}
+		}
+	}
+
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	return combineHandlers(combineHandlers(router.middlewares, group.chain()...), handler)
+}
+
+// withMiddlewares prepends the router's middlewares to handlers, for the
+// responses that are not produced by a route.
+func (router *Router) withMiddlewares(handlers ...context.HandlerFunc) context.HandlersChain {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	return combineHandlers(router.middlewares, handlers...)
+}
+
+// optionsHandler answers an OPTIONS request for a path without an OPTIONS
+// route; the Allow header is already set.
+func optionsHandler(c *context.Context) {
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+func (router *Router) setFallback(prefix string, notFound, methodNotAllowed *groupHandler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	for _, fb := range router.fallbacks {
+		if fb.prefix == prefix {
+			if notFound != nil {
+				fb.notFound = notFound
+			}
+			if methodNotAllowed != nil {
+				fb.methodNotAllowed = methodNotAllowed
+			}
+			return
+		}
+	}
+	router.fallbacks = append(router.fallbacks, &fallback{
+		prefix:           prefix,
+		notFound:         notFound,
+		methodNotAllowed: methodNotAllowed,
+	})
+}
+
+func (router *Router) matchOptions() matchOptions {
+	return matchOptions{strict: router.StrictRouting, caseSensitive: router.CaseSensitive}
+}
+
+func (router *Router) find(method, path string, params *context.Params) *node {
+	return router.findWith(method, path, router.matchOptions(), params)
+}
+
+func (router *Router) findWith(method, path string, opts matchOptions, params *context.Params) *node {
+	tree, ok := router.trees[method]
+	if !ok {
+		return nil
+	}
+	if path == "" {
+		path = "/"
+	}
+	return tree.find(path, 1, opts, params)
+}
+
+// findAny looks path up the way ServeHTTP would for method, including the
+// HEAD to GET and "ALL" fallbacks.
+func (router *Router) findAny(method, path string, opts matchOptions, params *context.Params) *node {
+	if n := router.findWith(method, path, opts, params); n != nil {
+		return n
+	}
+	if method == http.MethodHead && router.HandleHEAD {
+		if n := router.findWith(http.MethodGet, path, opts, params); n != nil {
+			return n
+		}
 	}
-	node.handler(ctx)
+	return router.findWith("ALL", path, opts, params)
 }
 
-func (g *Group) Group(prefix string, middlewares ...context.Middleware) *Group {
-	return &Group{
-		prefix:      g.prefix + prefix,
-		router:      g.router,
-		middlewares: append(g.middlewares, middlewares...),
+// redirectPath returns the canonical location for a path that did not match
+// as is, according to RedirectTrailingSlash and RedirectFixedPath.
+func (router *Router) redirectPath(method, path string) (string, bool) {
+	if router.RedirectTrailingSlash && router.StrictRouting && len(path) > 1 {
+		alt := path + "/"
+		if strings.HasSuffix(path, "/") {
+			alt = path[:len(path)-1]
+		}
+		var params context.Params
+		if n := router.findAny(method, alt, router.matchOptions(), &params); n != nil {
+			return alt, true
+		}
+	}
+
+	if router.RedirectFixedPath {
+		cleaned := pathpkg.Clean("/" + path)
+		if strings.HasSuffix(path, "/") && cleaned != "/" {
+			cleaned += "/"
+		}
+		opts := router.matchOptions()
+		opts.caseSensitive = false
+		var params context.Params
+		n := router.findAny(method, cleaned, opts, &params)
+		if n == nil {
+			return "", false
+		}
+		fixed := buildPath(splitPattern(n.route.Path, router.StrictRouting), params)
+		if !router.StrictRouting && strings.HasSuffix(cleaned, "/") && !strings.HasSuffix(fixed, "/") {
+			fixed += "/"
+		}
+		if fixed != path {
+			return fixed, true
+		}
 	}
+
+	return "", false
 }
 
-func (g *Group) GET(path string, handler context.HandlerFunc) *Route {
-	fullPath := g.prefix + path
-	return g.router.GET(fullPath, g.wrapWithMiddleware(handler))
+// allowed returns the comma-separated list of methods registered for path,
+// including the ones the router answers automatically.
+func (router *Router) allowed(path string) string {
+	methods := make([]string, 0, len(router.trees)+2)
+	for method := range router.trees {
+		if method == "ALL" {
+			continue
+		}
+		var params context.Params
+		if n := router.find(method, path, &params); n != nil {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return ""
+	}
+
+	has := func(method string) bool {
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+		return false
+	}
+	if router.HandleHEAD && has(http.MethodGet) && !has(http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if router.HandleOPTIONS && !has(http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+// headResponseWriter discards the body written by a GET handler serving a
+// HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
 }
 
-func (g *Group) POST(path string, handler context.HandlerFunc) *Route {
-	fullPath := g.prefix + path
-	return g.router.POST(fullPath, g.wrapWithMiddleware(handler))
+// combineHandlers returns a new chain of middlewares followed by handlers.
+func combineHandlers(middlewares context.HandlersChain, handlers ...context.HandlerFunc) context.HandlersChain {
+	chain := make(context.HandlersChain, 0, len(middlewares)+len(handlers))
+	chain = append(chain, middlewares...)
+	return append(chain, handlers...)
 }
 
-func (g *Group) wrapWithMiddleware(handler context.HandlerFunc) context.HandlerFunc {
-	currentHandler := handler
-	for i := len(g.middlewares) - 1; i >= 0; i-- {
-		currentHandler = g.middlewares[i](currentHandler)
+func adaptMiddlewares(middlewares []context.Middleware) context.HandlersChain {
+	handlers := make(context.HandlersChain, len(middlewares))
+	for i, m := range middlewares {
+		handlers[i] = context.FromMiddleware(m)
 	}
-	return currentHandler
+	return handlers
 }
 
-// helperss might move to utils
-func parsePath(path string) []string {
-	parts := strings.Split(path, "/")
-	cleanedParts := make([]string, 0)
-	for _, p := range parts {
-		if p != "" {
-			cleanedParts = append(cleanedParts, p)
+// hasPathPrefix reports whether path is prefix or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+// splitPattern splits a route pattern into its segments. "/" is a single
+// empty segment; in strict mode a trailing slash adds a final empty segment.
+func splitPattern(path string, strict bool) []string {
+	if !strict && len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// buildPath renders pattern segments back into a path, substituting params.
+func buildPath(parts []string, params context.Params) string {
+	var b strings.Builder
+	for _, part := range parts {
+		if segmentKind(part) == staticNode {
+			b.WriteByte('/')
+			b.WriteString(part)
+			continue
 		}
+		value, ok := params.Get(paramName(part))
+		if !ok {
+			// an omitted optional parameter
+			continue
+		}
+		b.WriteByte('/')
+		b.WriteString(value)
+	}
+	if b.Len() == 0 {
+		return "/"
 	}
-	return cleanedParts
+	return b.String()
 }
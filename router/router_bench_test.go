@@ -0,0 +1,75 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/router"
+)
+
+// discardWriter is a ResponseWriter that allocates nothing per request.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func newBenchRouter() *router.Router {
+	noop := func(c *context.Context) {}
+	r := router.New()
+	r.GET("/", noop)
+	r.GET("/users", noop)
+	r.GET("/users/me", noop)
+	r.GET("/users/:id", noop)
+	r.GET("/users/:id/posts/:post", noop)
+	r.GET("/static/*filepath", noop)
+	r.POST("/users", noop)
+	return r
+}
+
+func benchmarkRoute(b *testing.B, path string) {
+	r := newBenchRouter()
+	req := httptest.NewRequest("GET", path, nil)
+	w := &discardWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkRouter_Static(b *testing.B) {
+	benchmarkRoute(b, "/users/me")
+}
+
+func BenchmarkRouter_Param(b *testing.B) {
+	benchmarkRoute(b, "/users/42")
+}
+
+func BenchmarkRouter_TwoParams(b *testing.B) {
+	benchmarkRoute(b, "/users/42/posts/7")
+}
+
+func BenchmarkRouter_CatchAll(b *testing.B) {
+	benchmarkRoute(b, "/static/css/site.css")
+}
+
+func TestRouter_ZeroAllocations(t *testing.T) {
+	r := newBenchRouter()
+	w := &discardWriter{header: make(http.Header)}
+
+	for _, path := range []string{"/users/me", "/users/42", "/users/42/posts/7", "/static/css/site.css"} {
+		req := httptest.NewRequest("GET", path, nil)
+		allocs := testing.AllocsPerRun(100, func() {
+			r.ServeHTTP(w, req)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected 0 allocations, got %v", path, allocs)
+		}
+	}
+}
@@ -0,0 +1,525 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/arthurlch/goryu/context"
+	"github.com/arthurlch/goryu/router"
+)
+
+func textHandler(body string) context.HandlerFunc {
+	return func(c *context.Context) {
+		_ = c.Text(http.StatusOK, body)
+	}
+}
+
+func serve(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r := router.New()
+	r.GET("/users/:id", textHandler("get"))
+	r.PUT("/users/:id", textHandler("put"))
+
+	rr := serve(r, "DELETE", "/users/1")
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+	if allow := rr.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, PUT" {
+		t.Errorf("expected Allow 'GET, HEAD, OPTIONS, PUT', got '%s'", allow)
+	}
+
+	rr = serve(r, "DELETE", "/missing")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for unknown path, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	r.HandleMethodNotAllowed = false
+	rr = serve(r, "DELETE", "/users/1")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected status %d when disabled, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestRouter_AutoOptions(t *testing.T) {
+	r := router.New()
+	r.GET("/items", textHandler("get"))
+	r.POST("/items", textHandler("post"))
+	r.OPTIONS("/custom", textHandler("custom options"))
+
+	rr := serve(r, "OPTIONS", "/items")
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if allow := rr.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("expected Allow 'GET, HEAD, OPTIONS, POST', got '%s'", allow)
+	}
+
+	rr = serve(r, "OPTIONS", "/custom")
+	if rr.Body.String() != "custom options" {
+		t.Errorf("expected explicit OPTIONS handler to run, got '%s'", rr.Body.String())
+	}
+
+	r.HandleOPTIONS = false
+	rr = serve(r, "OPTIONS", "/items")
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d when disabled, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
+
+func TestRouter_HeadFromGet(t *testing.T) {
+	r := router.New()
+	r.GET("/page", func(c *context.Context) {
+		c.Writer.Header().Set("X-Page", "1")
+		_ = c.Text(http.StatusOK, "body")
+	})
+
+	rr := serve(r, "HEAD", "/page")
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if rr.Header().Get("X-Page") != "1" {
+		t.Error("expected headers from the GET handler")
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected empty body, got '%s'", rr.Body.String())
+	}
+
+	r.HandleHEAD = false
+	rr = serve(r, "HEAD", "/page")
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d when disabled, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
+
+func TestRouter_GroupFallbacks(t *testing.T) {
+	r := router.New()
+	r.NotFound = textHandler("root not found")
+
+	tagged := func(next context.HandlerFunc) context.HandlerFunc {
+		return func(c *context.Context) {
+			c.Writer.Header().Set("X-Group", "api")
+			next(c)
+		}
+	}
+	api := r.Group("/api", tagged)
+	api.GET("/users", textHandler("users"))
+	api.NotFound(textHandler("api not found"))
+	api.MethodNotAllowed(textHandler("api method not allowed"))
+
+	rr := serve(r, "GET", "/api/missing")
+	if rr.Body.String() != "api not found" {
+		t.Errorf("expected group NotFound handler, got '%s'", rr.Body.String())
+	}
+	if rr.Header().Get("X-Group") != "api" {
+		t.Error("expected group middleware to wrap the NotFound handler")
+	}
+
+	rr = serve(r, "POST", "/api/users")
+	if rr.Body.String() != "api method not allowed" {
+		t.Errorf("expected group MethodNotAllowed handler, got '%s'", rr.Body.String())
+	}
+	if rr.Header().Get("Allow") == "" {
+		t.Error("expected Allow header to be set before the handler runs")
+	}
+
+	rr = serve(r, "GET", "/apis")
+	if rr.Body.String() != "root not found" {
+		t.Errorf("expected root NotFound handler outside the group prefix, got '%s'", rr.Body.String())
+	}
+}
+
+func TestRouter_StrictRouting(t *testing.T) {
+	r := router.New()
+	r.GET("/users", textHandler("users"))
+	r.GET("/posts/", textHandler("posts"))
+
+	for _, path := range []string{"/users", "/users/", "/posts", "/posts/"} {
+		if rr := serve(r, "GET", path); rr.Code != http.StatusOK {
+			t.Errorf("non-strict: expected %s to match, got %d", path, rr.Code)
+		}
+	}
+
+	r = router.New()
+	r.StrictRouting = true
+	r.GET("/users", textHandler("users"))
+	r.GET("/users/", textHandler("users slash"))
+	r.GET("/posts/", textHandler("posts"))
+
+	if rr := serve(r, "GET", "/users/"); rr.Body.String() != "users slash" {
+		t.Errorf("strict: expected '/users/' handler, got '%s'", rr.Body.String())
+	}
+	if rr := serve(r, "GET", "/users"); rr.Body.String() != "users" {
+		t.Errorf("strict: expected '/users' handler, got '%s'", rr.Body.String())
+	}
+	if rr := serve(r, "GET", "/posts"); rr.Code != http.StatusNotFound {
+		t.Errorf("strict: expected 404 for '/posts', got %d", rr.Code)
+	}
+}
+
+func TestRouter_CaseSensitive(t *testing.T) {
+	r := router.New()
+	r.GET("/Users/:name", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.Param("name"))
+	})
+
+	if rr := serve(r, "GET", "/users/Alice"); rr.Body.String() != "Alice" {
+		t.Errorf("case-insensitive: expected 'Alice', got %d '%s'", rr.Code, rr.Body.String())
+	}
+
+	r = router.New()
+	r.CaseSensitive = true
+	r.GET("/Users", textHandler("users"))
+	if rr := serve(r, "GET", "/users"); rr.Code != http.StatusNotFound {
+		t.Errorf("case-sensitive: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRouter_RedirectTrailingSlash(t *testing.T) {
+	r := router.New()
+	r.StrictRouting = true
+	r.RedirectTrailingSlash = true
+	r.GET("/users", textHandler("users"))
+	r.POST("/posts/", textHandler("posts"))
+
+	rr := serve(r, "GET", "/users/")
+	if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != "/users" {
+		t.Errorf("expected 301 to '/users', got %d '%s'", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = serve(r, "POST", "/posts?draft=1")
+	if rr.Code != http.StatusPermanentRedirect || rr.Header().Get("Location") != "/posts/?draft=1" {
+		t.Errorf("expected 308 to '/posts/?draft=1', got %d '%s'", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestRouter_RedirectFixedPath(t *testing.T) {
+	r := router.New()
+	r.CaseSensitive = true
+	r.RedirectFixedPath = true
+	r.GET("/Users/:id", textHandler("user"))
+
+	tests := map[string]string{
+		"/users/42":         "/Users/42",
+		"//Users///42":      "/Users/42",
+		"/blog/../users/42": "/Users/42",
+		"/Users/./42":       "/Users/42",
+	}
+	for path, want := range tests {
+		rr := serve(r, "GET", path)
+		if rr.Code != http.StatusMovedPermanently || rr.Header().Get("Location") != want {
+			t.Errorf("%s: expected 301 to '%s', got %d '%s'", path, want, rr.Code, rr.Header().Get("Location"))
+		}
+	}
+
+	if rr := serve(r, "GET", "/Users/42"); rr.Code != http.StatusOK {
+		t.Errorf("expected canonical path to be served, got %d", rr.Code)
+	}
+}
+
+func TestRouter_StaticOverParamPriority(t *testing.T) {
+	r := router.New()
+	r.GET("/users/:id", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "param "+c.Param("id"))
+	})
+	r.GET("/users/me", textHandler("me"))
+	r.GET("/users/*rest", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "rest "+c.Param("rest"))
+	})
+	r.GET("/files/:name/raw", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, "raw "+c.Param("name"))
+	})
+	r.GET("/files/latest/info", textHandler("latest info"))
+
+	tests := map[string]string{
+		"/users/me":          "me",
+		"/users/42":          "param 42",
+		"/users/42/posts/1":  "rest 42/posts/1",
+		"/files/latest/raw":  "raw latest",
+		"/files/latest/info": "latest info",
+	}
+	for path, want := range tests {
+		if rr := serve(r, "GET", path); rr.Body.String() != want {
+			t.Errorf("%s: expected '%s', got %d '%s'", path, want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestRouter_RegistrationConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []string
+	}{
+		{"duplicate route", []string{"/users", "/users"}},
+		{"duplicate route ignoring case", []string{"/users", "/Users"}},
+		{"different parameter names", []string{"/a/:id", "/a/:name"}},
+		{"different catch-all names", []string{"/s/*path", "/s/*file"}},
+		{"catch-all not last", []string{"/s/*path/more"}},
+		{"unnamed parameter", []string{"/a/:"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registration of %v to panic", tt.routes)
+				}
+			}()
+			r := router.New()
+			for _, path := range tt.routes {
+				r.GET(path, textHandler("ok"))
+			}
+		})
+	}
+
+	r := router.New()
+	r.GET("/a/:id", textHandler("get"))
+	r.POST("/a/:id", textHandler("post"))
+	r.GET("/a/:id/b", textHandler("nested"))
+}
+
+func TestRouter_ParamConstraints(t *testing.T) {
+	echo := func(prefix, name string) context.HandlerFunc {
+		return func(c *context.Context) {
+			_ = c.Text(http.StatusOK, prefix+" "+c.Param(name))
+		}
+	}
+
+	r := router.New()
+	r.GET("/users/:id<int>", echo("id", "id"))
+	r.GET("/users/:name", echo("name", "name"))
+	r.GET("/files/:name<[a-z0-9_-]+>", echo("file", "name"))
+	r.GET("/v/:uuid<uuid>", echo("uuid", "uuid"))
+	r.GET("/posts/:slug?", func(c *context.Context) {
+		slug, ok := c.Params.Get("slug")
+		if !ok {
+			slug = "index"
+		}
+		_ = c.Text(http.StatusOK, "post "+slug)
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", http.StatusOK, "id 42"},
+		{"/users/alice", http.StatusOK, "name alice"},
+		{"/files/report_2024-v1", http.StatusOK, "file report_2024-v1"},
+		{"/files/Report.pdf", http.StatusNotFound, ""},
+		{"/v/3f2b8e4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b", http.StatusOK, "uuid 3f2b8e4a-1c2d-4e5f-8a9b-0c1d2e3f4a5b"},
+		{"/v/3F2B8E4A-1C2D-4E5F-8A9B-0C1D2E3F4A5B", http.StatusOK, "uuid 3F2B8E4A-1C2D-4E5F-8A9B-0C1D2E3F4A5B"},
+		{"/v/not-a-uuid", http.StatusNotFound, ""},
+		{"/v/%10%10%10%10%10%10%10%10-0000-0000-0000-000000000000", http.StatusNotFound, ""},
+		{"/posts/hello", http.StatusOK, "post hello"},
+		{"/posts", http.StatusOK, "post index"},
+	}
+	for _, tt := range tests {
+		rr := serve(r, "GET", tt.path)
+		if rr.Code != tt.code {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.code, rr.Code)
+			continue
+		}
+		if tt.body != "" && rr.Body.String() != tt.body {
+			t.Errorf("%s: expected '%s', got '%s'", tt.path, tt.body, rr.Body.String())
+		}
+	}
+}
+
+func TestRouter_ConstraintRegistration(t *testing.T) {
+	r := router.New()
+	r.GET("/a/:id<int>", textHandler("int"))
+	r.GET("/a/:slug<alpha>", textHandler("alpha"))
+
+	for _, path := range []string{"/b/:id<[a-z>", "/c/:id<>", "/d/:id?/e"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registration of %s to panic", path)
+				}
+			}()
+			r.GET(path, textHandler("bad"))
+		}()
+	}
+}
+
+func TestRouter_Reverse(t *testing.T) {
+	r := router.New()
+	r.GET("/users/:id<int>", textHandler("user")).SetName("user")
+	r.GET("/users/:id/files/*path", textHandler("file")).SetName("user.file")
+	r.GET("/search/:term", textHandler("search")).SetName("search")
+	r.GET("/posts/:slug?", textHandler("posts")).SetName("posts")
+	api := r.Group("/api")
+	api.GET("/status", textHandler("status")).SetName("api.status")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		want   string
+	}{
+		{"user", []interface{}{42}, "/users/42"},
+		{"user", []interface{}{"abc"}, ""},
+		{"user", nil, ""},
+		{"user", []interface{}{map[string]interface{}{"id": 7, "tab": "posts"}}, "/users/7?tab=posts"},
+		{"user.file", []interface{}{1, "docs/a b.txt"}, "/users/1/files/docs/a%20b.txt"},
+		{"search", []interface{}{"go lang/2", url.Values{"page": {"2"}}}, "/search/go%20lang%2F2?page=2"},
+		{"posts", nil, "/posts"},
+		{"posts", []interface{}{"hello"}, "/posts/hello"},
+		{"api.status", nil, "/api/status"},
+		{"missing", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := r.Reverse(tt.name, tt.params...); got != tt.want {
+			t.Errorf("Reverse(%q, %v): expected '%s', got '%s'", tt.name, tt.params, tt.want, got)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected duplicate route name to panic")
+			}
+		}()
+		r.GET("/other", textHandler("other")).SetName("user")
+	}()
+}
+
+func TestContext_URLFor(t *testing.T) {
+	r := router.New()
+	r.GET("/users/:id", textHandler("user")).SetName("user")
+	r.GET("/link", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.URLFor("user", 5))
+	})
+
+	if rr := serve(r, "GET", "/link"); rr.Body.String() != "/users/5" {
+		t.Errorf("expected '/users/5', got '%s'", rr.Body.String())
+	}
+}
+
+func TestGroup_Verbs(t *testing.T) {
+	r := router.New()
+	g := r.Group("/api")
+	g.GET("/items", textHandler("GET"))
+	g.POST("/items", textHandler("POST"))
+	g.PUT("/items", textHandler("PUT"))
+	g.DELETE("/items", textHandler("DELETE"))
+	g.PATCH("/items", textHandler("PATCH"))
+	g.HEAD("/items", textHandler("HEAD"))
+	g.OPTIONS("/items", textHandler("OPTIONS"))
+	g.ALL("/any", textHandler("ALL"))
+
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"} {
+		if rr := serve(r, method, "/api/items"); rr.Body.String() != method {
+			t.Errorf("%s: expected '%s', got '%s'", method, method, rr.Body.String())
+		}
+	}
+	if rr := serve(r, "PATCH", "/api/any"); rr.Body.String() != "ALL" {
+		t.Errorf("expected ALL handler, got '%s'", rr.Body.String())
+	}
+}
+
+func TestGroup_MiddlewareIsolation(t *testing.T) {
+	tag := func(value string) context.Middleware {
+		return func(next context.HandlerFunc) context.HandlerFunc {
+			return func(c *context.Context) {
+				c.Writer.Header().Add("X-Tag", value)
+				next(c)
+			}
+		}
+	}
+
+	r := router.New()
+	// A parent slice with spare capacity used to be shared by its children.
+	parent := r.Group("/p", tag("parent"))
+	parent.Use(tag("parent2"))
+	a := parent.Group("/a", tag("a"))
+	b := parent.Group("/b", tag("b"))
+	a.GET("/x", textHandler("a"))
+	b.GET("/x", textHandler("b"))
+
+	parent.Route("/c", func(g *router.Group) {
+		g.Use(tag("c"))
+		g.GET("/x", textHandler("c"))
+	})
+
+	tests := map[string][]string{
+		"/p/a/x": {"parent", "parent2", "a"},
+		"/p/b/x": {"parent", "parent2", "b"},
+		"/p/c/x": {"parent", "parent2", "c"},
+	}
+	for path, want := range tests {
+		got := serve(r, "GET", path).Header().Values("X-Tag")
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected middleware %v, got %v", path, want, got)
+		}
+	}
+}
+
+func TestGroup_UseAppliesRegardlessOfOrder(t *testing.T) {
+	tag := func(value string) context.Middleware {
+		return func(next context.HandlerFunc) context.HandlerFunc {
+			return func(c *context.Context) {
+				c.Writer.Header().Add("X-Tag", value)
+				next(c)
+			}
+		}
+	}
+
+	r := router.New()
+	g := r.Group("/api")
+	g.GET("/before", textHandler("before"))
+	child := g.Group("/v1")
+	child.GET("/x", textHandler("x"))
+	g.NotFound(textHandler("missing"))
+	g.Use(tag("api"))
+	child.Use(tag("v1"))
+
+	tests := map[string][]string{
+		"/api/before":  {"api"},
+		"/api/v1/x":    {"api", "v1"},
+		"/api/missing": {"api"},
+	}
+	for path, want := range tests {
+		got := serve(r, "GET", path).Header().Values("X-Tag")
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s: expected middleware %v, got %v", path, want, got)
+		}
+	}
+}
+
+func TestGroup_MountAndStatic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("js"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := router.New()
+	sub.GET("/ping", func(c *context.Context) {
+		_ = c.Text(http.StatusOK, c.Request.URL.Path)
+	}).SetName("ping")
+
+	r := router.New()
+	g := r.Group("/api")
+	g.Mount("/v2", sub)
+	g.Static("/assets", dir)
+
+	if rr := serve(r, "GET", "/api/v2/ping"); rr.Body.String() != "/ping" {
+		t.Errorf("expected mounted router to see '/ping', got '%s'", rr.Body.String())
+	}
+	if got := r.Reverse("ping"); got != "/api/v2/ping" {
+		t.Errorf("expected reversed path '/api/v2/ping', got '%s'", got)
+	}
+	if rr := serve(r, "GET", "/api/assets/app.js"); rr.Body.String() != "js" {
+		t.Errorf("expected static file, got %d '%s'", rr.Code, rr.Body.String())
+	}
+}
@@ -1,76 +1,186 @@
 package router
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/arthurlch/goryu/context"
 )
 
-// SUing Radix Tree
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	catchAllNode
+)
+
+// Routing tree keyed by path segment. Lookups try static children first,
+// then the parameter children (constrained ones before the plain one), then
+// the catch-all, backtracking when a branch does not lead to a route.
 type node struct {
+	// path is the pattern of the route that created the node, used in
+	// conflict messages.
 	path     string
 	part     string
+	kind     nodeKind
 	children []*node
-	isWild   bool
-	handler  context.HandlerFunc
+	params   []*node
+	catchAll *node
 	route    *Route
+
+	// name and constraint describe parameter and catch-all nodes.
+	name       string
+	expr       string
+	constraint func(string) bool
+}
+
+// matchOptions carries the router settings that affect matching.
+type matchOptions struct {
+	strict        bool
+	caseSensitive bool
+}
+
+func (o matchOptions) equal(a, b string) bool {
+	if o.caseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+func segmentKind(part string) nodeKind {
+	switch {
+	case strings.HasPrefix(part, ":"):
+		return paramNode
+	case strings.HasPrefix(part, "*"):
+		return catchAllNode
+	default:
+		return staticNode
+	}
 }
 
-func (n *node) insert(path string, parts []string, height int, route *Route) {
+// insert adds route under n, panicking when it is ambiguous with or
+// duplicates an existing route.
+func (n *node) insert(path string, parts []string, height int, route *Route, opts matchOptions) {
 	if len(parts) == height {
-		n.path = path
-		n.handler = route.Handler
+		if n.route != nil {
+			panic(fmt.Sprintf("router: %s %s is already registered", route.Method, n.route.Path))
+		}
 		n.route = route
 		return
 	}
 
 	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+	var child *node
+
+	switch segmentKind(part) {
+	case paramNode:
+		seg, err := parseParam(part)
+		if err != nil {
+			panic(fmt.Sprintf("router: %v in %s", err, path))
+		}
+		for _, p := range n.params {
+			if p.expr != seg.expr {
+				continue
+			}
+			if p.name != seg.name {
+				panic(fmt.Sprintf("router: parameter %q in %s conflicts with %q in %s", part, path, p.part, p.path))
+			}
+			child = p
+		}
+		if child == nil {
+			child = &node{path: path, part: part, kind: paramNode, name: seg.name, expr: seg.expr, constraint: seg.constraint}
+			// The unconstrained parameter is tried last.
+			if seg.constraint != nil && len(n.params) > 0 && n.params[len(n.params)-1].constraint == nil {
+				last := n.params[len(n.params)-1]
+				n.params = append(n.params[:len(n.params)-1], child, last)
+			} else {
+				n.params = append(n.params, child)
+			}
+		}
+	case catchAllNode:
+		if height != len(parts)-1 {
+			panic(fmt.Sprintf("router: catch-all %q must be the last segment in %s", part, path))
+		}
+		if len(part) == 1 {
+			panic(fmt.Sprintf("router: unnamed catch-all in %s", path))
+		}
+		if n.catchAll != nil && n.catchAll.part != part {
+			panic(fmt.Sprintf("router: catch-all %q in %s conflicts with %q in %s", part, path, n.catchAll.part, n.catchAll.path))
+		}
+		if n.catchAll == nil {
+			n.catchAll = &node{path: path, part: part, kind: catchAllNode, name: part[1:]}
+		}
+		child = n.catchAll
+	default:
+		for _, c := range n.children {
+			if opts.equal(c.part, part) {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &node{path: path, part: part, kind: staticNode}
+			n.children = append(n.children, child)
+		}
 	}
-	child.insert(path, parts, height+1, route)
+
+	child.insert(path, parts, height+1, route, opts)
 }
 
-func (n *node) find(parts []string, height int) (*node, map[string]string) {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.handler == nil {
-			return nil, nil
+// find walks path one segment at a time without splitting it, appending
+// matched parameters to params. i is the index where the next segment starts;
+// i > len(path) means every segment has been consumed.
+func (n *node) find(path string, i int, opts matchOptions, params *context.Params) *node {
+	if i > len(path) || (!opts.strict && i == len(path) && i > 1) {
+		if n.route != nil {
+			return n
 		}
-		params := make(map[string]string)
-		if strings.HasPrefix(n.part, "*") {
-			params[n.part[1:]] = strings.Join(parts[height:], "/")
+		// "/static/" still reaches "/static/*filepath" with an empty value.
+		if i == len(path) && n.catchAll != nil && n.catchAll.route != nil {
+			*params = append(*params, context.Param{Key: n.catchAll.name})
+			return n.catchAll
 		}
-		return n, params
+		return nil
 	}
 
-	part := parts[height]
-	params := make(map[string]string)
+	end := strings.IndexByte(path[i:], '/')
+	next := len(path) + 1
+	if end < 0 {
+		end = len(path)
+	} else {
+		end += i
+		next = end + 1
+	}
+	part := path[i:end]
 
 	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			if child.isWild {
-				params[child.part[1:]] = part
+		if !opts.equal(child.part, part) {
+			continue
+		}
+		if result := child.find(path, next, opts, params); result != nil {
+			return result
+		}
+	}
+
+	if part != "" {
+		for _, child := range n.params {
+			if child.constraint != nil && !child.constraint(part) {
+				continue
 			}
-			result, foundParams := child.find(parts, height+1)
-			if result != nil {
-				for k, v := range foundParams {
-					params[k] = v
-				}
-				return result, params
+			mark := len(*params)
+			*params = append(*params, context.Param{Key: child.name, Value: part})
+			if result := child.find(path, next, opts, params); result != nil {
+				return result
 			}
+			*params = (*params)[:mark]
 		}
 	}
 
-	return nil, nil
-}
-
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
-		}
+	if n.catchAll != nil && n.catchAll.route != nil {
+		*params = append(*params, context.Param{Key: n.catchAll.name, Value: path[i:]})
+		return n.catchAll
 	}
+
 	return nil
 }